@@ -0,0 +1,397 @@
+package gopherman
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// anonymousHeaders are removed from the requests of anonymous identities
+var anonymousHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// Identity is a set of credentials to replay a collection as. See Tester.AuthorizationMatrix
+type Identity struct {
+	Name string
+
+	// Environment holds the identity's credentials, overlaid on the tester's environment
+	Environment *postman.Environment
+	// Cookies seed the identity's own cookie jar
+	Cookies []postman.Cookie
+	// Anonymous identities send requests without Authorization, Cookie or Proxy-Authorization headers
+	Anonymous bool
+
+	// Level ranks identities by privilege. Identities other than the owner whose Level is no
+	// higher than the owner's must not get the owner's success responses
+	Level int
+	// Owner marks the identity that owns the collection's resources. Exactly one identity must be the owner
+	Owner bool
+}
+
+// IdentityFromFile returns an identity whose credentials are in an environment file
+func IdentityFromFile(name, path string, level int) (*Identity, error) {
+	env, err := postman.EnvironmentFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{Name: name, Environment: env, Level: level}, nil
+}
+
+// AuthzCell is the outcome of one item's request as one identity
+type AuthzCell struct {
+	Status int
+	Error  string
+	// Violation is true if the identity shouldn't have succeeded
+	Violation bool
+}
+
+// AuthzRow is the outcome of one item's request as each identity, by identity name
+type AuthzRow struct {
+	Collection string
+	Item       string
+	Method     string
+	Cells      map[string]AuthzCell
+}
+
+// AuthzViolation is a request where an identity got the same success response as the owner
+type AuthzViolation struct {
+	Collection string
+	Item       string
+	Method     string
+	Identity   string
+	Status     int
+}
+
+func (v AuthzViolation) String() string {
+	return fmt.Sprintf("%s/%s: %s got %d like the owner", v.Collection, v.Item, v.Identity, v.Status)
+}
+
+// AuthzMatrix holds the status codes of every item's request as every identity
+type AuthzMatrix struct {
+	Identities []string
+	Rows       []AuthzRow
+	Violations []AuthzViolation
+}
+
+// AuthorizationMatrix sends every item's request once as each identity, and flags requests where an
+// identity other than the owner, with a Level no higher than the owner's, gets the same 2xx response
+// as the owner, compared with t.Comparator.
+//
+// Values extracted from responses come from the owner's requests only, so every identity requests the
+// owner's resources. Each item is sent as the owner first, except DELETE requests, which are sent as
+// the identities with a Level no higher than the owner's first so that the owner's resource still
+// exists, then as the owner, then as the higher-privileged identities; any success deleting it
+// by a lower-privileged identity is flagged
+func (t *Tester) AuthorizationMatrix(ctx context.Context, identities []Identity) (*AuthzMatrix, error) {
+	owner := -1
+	for i := range identities {
+		if identities[i].Owner {
+			if owner >= 0 {
+				return nil, errors.New("only one identity can be the owner")
+			}
+
+			owner = i
+		}
+	}
+
+	if owner < 0 {
+		return nil, errors.New("one identity must be the owner")
+	}
+
+	if err := t.WaitReady(ctx); err != nil {
+		return nil, err
+	}
+
+	actors := make([]*authzActor, len(identities))
+	for i := range identities {
+		actor, err := t.newAuthzActor(&identities[i])
+		if err != nil {
+			return nil, err
+		}
+
+		actors[i] = actor
+	}
+
+	matrix := &AuthzMatrix{}
+	for _, id := range identities {
+		matrix.Identities = append(matrix.Identities, id.Name)
+	}
+
+	for i := range t.Collections {
+		collection := &t.Collections[i]
+
+		collectionVars, err := collection.ResolvedVariableMap(t.Secrets)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to resolve collection variables")
+		}
+
+		// the owner's runtime values are shared by every identity, so they all request the same resources
+		local := t.Vars.LocalCopy()
+
+		collection.Walk(func(folders []string, itm *postman.CollectionItem) {
+			if ctx.Err() != nil {
+				return
+			}
+
+			path := strings.Join(append(append([]string{}, folders...), itm.Name), "/")
+			row := AuthzRow{
+				Collection: collection.Info.Name,
+				Item:       path,
+				Method:     strings.ToUpper(itm.Request.Method),
+				Cells:      map[string]AuthzCell{},
+			}
+
+			order := authzOrder(identities, owner, row.Method == http.MethodDelete)
+			responses := make([]*postman.Response, len(identities))
+
+			for _, a := range order {
+				scope := actors[a].scope(collectionVars, local)
+
+				resp, header, err := actors[a].send(ctx, &itm.Request, scope)
+				if err != nil {
+					row.Cells[identities[a].Name] = AuthzCell{Error: err.Error()}
+					continue
+				}
+
+				responses[a] = resp
+				row.Cells[identities[a].Name] = AuthzCell{Status: resp.Status}
+
+				if a == owner {
+					// failed extractions only matter to later items, which will show their own failures
+					extract(scope, t.extractionsFor(itm), resp, header)
+				}
+			}
+
+			for a := range identities {
+				if a == owner || identities[a].Level > identities[owner].Level {
+					continue
+				}
+
+				if t.authzViolation(row.Method, responses[owner], responses[a]) {
+					cell := row.Cells[identities[a].Name]
+					cell.Violation = true
+					row.Cells[identities[a].Name] = cell
+
+					matrix.Violations = append(matrix.Violations, AuthzViolation{
+						Collection: row.Collection,
+						Item:       row.Item,
+						Method:     row.Method,
+						Identity:   identities[a].Name,
+						Status:     responses[a].Status,
+					})
+				}
+			}
+
+			matrix.Rows = append(matrix.Rows, row)
+		})
+	}
+
+	return matrix, ctx.Err()
+}
+
+// RunAuthorization runs AuthorizationMatrix, logs the matrix, and fails tst with an error for each violation
+func (t *Tester) RunAuthorization(tst *testing.T, identities []Identity) *AuthzMatrix {
+	tst.Helper()
+
+	matrix, err := t.AuthorizationMatrix(context.Background(), identities)
+	if err != nil {
+		tst.Fatal(err)
+	}
+
+	tst.Log("\n" + matrix.String())
+
+	for _, v := range matrix.Violations {
+		tst.Error(v.String())
+	}
+
+	return matrix
+}
+
+// authzOrder returns the order to send an item as each identity: the owner first, or for deletes
+// after the identities whose Level is no higher than the owner's and before the rest, so that
+// only identities that are allowed to delete the owner's resource get the chance to
+func authzOrder(identities []Identity, owner int, deleting bool) []int {
+	if !deleting {
+		order := []int{owner}
+		for i := range identities {
+			if i != owner {
+				order = append(order, i)
+			}
+		}
+
+		return order
+	}
+
+	lower := []int{}
+	higher := []int{}
+
+	for i := range identities {
+		switch {
+		case i == owner:
+		case identities[i].Level <= identities[owner].Level:
+			lower = append(lower, i)
+		default:
+			higher = append(higher, i)
+		}
+	}
+
+	return append(append(lower, owner), higher...)
+}
+
+// authzViolation returns true if actual is a success that a lower-privileged identity shouldn't have got
+func (t *Tester) authzViolation(method string, owner, actual *postman.Response) bool {
+	if actual == nil || actual.Status < 200 || actual.Status > 299 {
+		return false
+	}
+
+	// a delete sent before the owner's can't be compared with it, and mustn't succeed at all
+	if method == http.MethodDelete {
+		return true
+	}
+
+	if owner == nil || owner.Status != actual.Status {
+		return false
+	}
+
+	comparator := t.Comparator
+	if comparator == nil {
+		comparator = NewComparator()
+	}
+
+	if strings.TrimSpace(owner.Raw) == "" || strings.TrimSpace(actual.Raw) == "" {
+		return strings.TrimSpace(owner.Raw) == strings.TrimSpace(actual.Raw)
+	}
+
+	return len(comparator.Compare([]byte(owner.Raw), []byte(actual.Raw))) == 0
+}
+
+// authzActor sends requests as one identity
+type authzActor struct {
+	tester      *Tester
+	identity    *Identity
+	environment map[string]string
+	jar         http.CookieJar
+}
+
+func (t *Tester) newAuthzActor(identity *Identity) (*authzActor, error) {
+	a := &authzActor{
+		tester:      t,
+		identity:    identity,
+		environment: map[string]string{},
+	}
+
+	for k, v := range t.Vars.Environment {
+		a.environment[k] = v
+	}
+
+	if identity.Environment != nil {
+		vars, err := identity.Environment.ResolvedVariableMap(t.Secrets)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve environment of identity %s", identity.Name)
+		}
+
+		for k, v := range vars {
+			a.environment[k] = v
+		}
+	}
+
+	jar, err := newCookieJar(identity.Cookies)
+	if err != nil {
+		return nil, err
+	}
+
+	a.jar = jar
+
+	return a, nil
+}
+
+// scope returns a scope with the identity's environment and the shared runtime values
+func (a *authzActor) scope(collectionVars, local map[string]string) *postman.Scope {
+	scope := a.tester.Vars.WithCollection(collectionVars).WithLocal(local)
+	scope.Environment = a.environment
+
+	return scope
+}
+
+func (a *authzActor) send(ctx context.Context, req *postman.Request, scope *postman.Scope) (*postman.Response, http.Header, error) {
+	if a.identity.Anonymous {
+		// removed before variables are substituted, since an anonymous identity may not define the credentials they use
+		anonymous := *req
+		anonymous.Header = []postman.Header{}
+
+		for _, h := range req.Header {
+			if !isAnonymousHeader(h.Key) && !isAnonymousHeader(h.Name) {
+				anonymous.Header = append(anonymous.Header, h)
+			}
+		}
+
+		req = &anonymous
+	}
+
+	httpReq, client, err := a.tester.buildRequest(req, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if a.identity.Anonymous {
+		for _, h := range anonymousHeaders {
+			httpReq.Header.Del(h)
+		}
+	}
+
+	withJar := *client
+	withJar.Jar = a.jar
+
+	return a.tester.attempt(ctx, &withJar, httpReq)
+}
+
+func isAnonymousHeader(name string) bool {
+	for _, h := range anonymousHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String formats the matrix as a table of status codes, with violations marked by !
+func (m *AuthzMatrix) String() string {
+	buf := &bytes.Buffer{}
+
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "item\tmethod\t%s\t\n", strings.Join(m.Identities, "\t"))
+
+	for _, row := range m.Rows {
+		cells := []string{}
+		for _, name := range m.Identities {
+			cell := row.Cells[name]
+
+			text := fmt.Sprint(cell.Status)
+			if cell.Error != "" {
+				text = "error"
+			}
+
+			if cell.Violation {
+				text += "!"
+			}
+
+			cells = append(cells, text)
+		}
+
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\t\n", row.Collection, row.Item, row.Method, strings.Join(cells, "\t"))
+	}
+
+	w.Flush()
+
+	fmt.Fprintf(buf, "%d violation(s)\n", len(m.Violations))
+
+	return buf.String()
+}
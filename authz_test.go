@@ -0,0 +1,162 @@
+package gopherman
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+const authzCollection = `{
+	"info": {"name": "docs"},
+	"item": [
+		{
+			"name": "create",
+			"request": {"method": "POST", "url": {"raw": "/docs"}, "header": [{"key": "Authorization", "value": "Bearer {{token}}"}]},
+			"response": [{"code": 201, "body": "{}"}],
+			"gopherman": {"extract": [{"variable": "docId", "path": "$.id"}]}
+		},
+		{
+			"name": "get",
+			"request": {"method": "GET", "url": {"raw": "/docs/{{docId}}"}, "header": [{"key": "Authorization", "value": "Bearer {{token}}"}]},
+			"response": [{"code": 200, "body": "{}"}]
+		},
+		{
+			"name": "delete",
+			"request": {"method": "DELETE", "url": {"raw": "/docs/{{docId}}"}, "header": [{"key": "Authorization", "value": "Bearer {{token}}"}]},
+			"response": [{"code": 204}]
+		}
+	]
+}`
+
+// docsHandler lets any signed in user read any doc, and lets bob delete them as well as their owner and admin
+func docsHandler() http.Handler {
+	mu := sync.Mutex{}
+	owners := map[string]string{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		user := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if user == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			id := fmt.Sprint(len(owners) + 1)
+			owners[id] = user
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"id":"%s"}`, id)
+			return
+		}
+
+		id := strings.TrimPrefix(r.URL.Path, "/docs/")
+
+		owner, ok := owners[id]
+		if !ok || owner == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet:
+			fmt.Fprintf(w, `{"id":"%s","owner":"%s"}`, id, owner)
+		case user != owner && user != "admin" && user != "bob":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			// keep the id taken, so that new docs get new ids
+			owners[id] = ""
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
+
+func tokenIdentity(name string, level int) Identity {
+	env := &postman.Environment{Values: []postman.Variable{{Key: "token", Value: name, Enabled: true}}}
+	return Identity{Name: name, Environment: env, Level: level}
+}
+
+func TestAuthorizationMatrix(t *testing.T) {
+	srv := httptest.NewServer(docsHandler())
+	defer srv.Close()
+
+	dir := writeTempFile(t, "docs.json", []byte(authzCollection))
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithServer(srv, dir, "", "docs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := tokenIdentity("alice", 1)
+	alice.Owner = true
+
+	// admin is listed before bob, but mustn't delete alice's doc before bob tries to
+	identities := []Identity{
+		{Name: "anonymous", Anonymous: true},
+		alice,
+		tokenIdentity("admin", 2),
+		tokenIdentity("bob", 1),
+	}
+
+	matrix, err := tester.AuthorizationMatrix(context.Background(), identities)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"anonymous", "alice", "admin", "bob"}, matrix.Identities)
+
+	statuses := map[string][]int{}
+	for _, row := range matrix.Rows {
+		for _, name := range matrix.Identities {
+			statuses[row.Item] = append(statuses[row.Item], row.Cells[name].Status)
+		}
+	}
+
+	assert.Equal(t, map[string][]int{
+		"create": {401, 201, 201, 201},
+		"get":    {401, 200, 200, 200},
+		"delete": {401, 404, 404, 204},
+	}, statuses)
+
+	violations := []string{}
+	for _, v := range matrix.Violations {
+		violations = append(violations, v.String())
+	}
+
+	assert.Equal(t, []string{"docs/get: bob got 200 like the owner", "docs/delete: bob got 204 like the owner"}, violations)
+	assert.Contains(t, matrix.String(), "2 violation(s)")
+}
+
+func TestAuthorizationMatrixOwner(t *testing.T) {
+	tester := &Tester{}
+
+	_, err := tester.AuthorizationMatrix(context.Background(), []Identity{{Name: "a"}, {Name: "b"}})
+	assert.EqualError(t, err, "one identity must be the owner")
+
+	_, err = tester.AuthorizationMatrix(context.Background(), []Identity{{Name: "a", Owner: true}, {Name: "b", Owner: true}})
+	assert.EqualError(t, err, "only one identity can be the owner")
+}
+
+func TestAuthzOrder(t *testing.T) {
+	identities := []Identity{
+		{Name: "admin", Level: 2},
+		{Name: "anonymous", Level: 0},
+		{Name: "owner", Level: 1, Owner: true},
+		{Name: "peer", Level: 1},
+		{Name: "root", Level: 3},
+	}
+
+	assert.Equal(t, []int{2, 0, 1, 3, 4}, authzOrder(identities, 2, false))
+	assert.Equal(t, []int{1, 3, 2, 0, 4}, authzOrder(identities, 2, true))
+}
@@ -0,0 +1,310 @@
+package gopherman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/cohix/gopherman/jsonschema"
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// APIChange is one difference between two recorded versions of an API
+type APIChange struct {
+	Method string
+	Path   string
+	// Where is "endpoint", "status", "request" or "response" followed by the status code
+	Where string
+	// Field is the JSON path of the changed field in the body, if any
+	Field       string `json:",omitempty"`
+	Description string
+	// Breaking is true if clients of the old version may fail against the new one
+	Breaking bool
+}
+
+func (c APIChange) String() string {
+	kind := "non-breaking"
+	if c.Breaking {
+		kind = "BREAKING"
+	}
+
+	where := c.Where
+	if c.Field != "" {
+		where += " " + c.Field
+	}
+
+	return fmt.Sprintf("%s %s %s %s: %s", kind, c.Method, c.Path, where, c.Description)
+}
+
+// ChangeReport lists the changes between two recorded versions of an API
+type ChangeReport struct {
+	Changes []APIChange
+}
+
+// DetectChanges compares collections recorded from two versions of an API, such as by RequestRecorder
+// on different dates or branches. Endpoints and body schemas are found as by InferSchemas, and each
+// change is classified as breaking if clients of the before version may fail against the after version:
+// removed endpoints, success statuses or response fields, retyped fields, response fields that
+// became optional, and request fields that became required
+func DetectChanges(before, after []*postman.Collection) *ChangeReport {
+	report := &ChangeReport{}
+
+	beforeEndpoints := InferSchemas(before...)
+	afterEndpoints := InferSchemas(after...)
+
+	afterByKey := map[string]*EndpointSchemas{}
+	for i := range afterEndpoints {
+		e := &afterEndpoints[i]
+		afterByKey[e.Method+" "+e.Path] = e
+	}
+
+	seen := map[string]bool{}
+
+	for i := range beforeEndpoints {
+		b := &beforeEndpoints[i]
+		key := b.Method + " " + b.Path
+		seen[key] = true
+
+		a, ok := afterByKey[key]
+		if !ok {
+			report.add(b, "endpoint", "", "removed", true)
+			continue
+		}
+
+		renamed := report.compareStatuses(b, a)
+		report.compareRequests(b, a)
+
+		for _, status := range b.Statuses {
+			afterStatus, ok := renamed[status]
+			if !ok {
+				afterStatus = status
+			}
+
+			if b.Responses[status] != nil && a.Responses[afterStatus] != nil {
+				report.compareResponses(b, status, b.Responses[status], a.Responses[afterStatus])
+			}
+		}
+	}
+
+	for i := range afterEndpoints {
+		a := &afterEndpoints[i]
+		if !seen[a.Method+" "+a.Path] {
+			report.add(a, "endpoint", "", "added", false)
+		}
+	}
+
+	return report
+}
+
+func (r *ChangeReport) add(e *EndpointSchemas, where, field, description string, breaking bool) {
+	r.Changes = append(r.Changes, APIChange{
+		Method:      e.Method,
+		Path:        e.Path,
+		Where:       where,
+		Field:       field,
+		Description: description,
+		Breaking:    breaking,
+	})
+}
+
+// compareStatuses reports statuses that are no longer or newly recorded. Losing a success status
+// is breaking, since clients check for it. If one success status replaced another, it's returned
+// keyed by the old one so that their responses can be compared
+func (r *ChangeReport) compareStatuses(before, after *EndpointSchemas) map[int]int {
+	removed, added := statusDifference(before.Statuses, after.Statuses), statusDifference(after.Statuses, before.Statuses)
+	removedSuccess, addedSuccess := successStatuses(removed), successStatuses(added)
+	renamed := map[int]int{}
+
+	if len(removedSuccess) == 1 && len(addedSuccess) == 1 {
+		renamed[removedSuccess[0]] = addedSuccess[0]
+	}
+
+	if len(removedSuccess) > 0 && len(addedSuccess) > 0 {
+		r.add(before, "status", "", fmt.Sprintf("success status %s became %s", joinInts(removedSuccess), joinInts(addedSuccess)), true)
+		removed, added = statusDifference(removed, removedSuccess), statusDifference(added, addedSuccess)
+	}
+
+	for _, status := range removed {
+		r.add(before, "status", "", fmt.Sprintf("%d no longer returned", status), status >= 200 && status < 300)
+	}
+
+	for _, status := range added {
+		r.add(before, "status", "", fmt.Sprintf("%d newly returned", status), false)
+	}
+
+	return renamed
+}
+
+// compareRequests reports changes to the request body. Fields that clients must now send,
+// or must send as another type, are breaking
+func (r *ChangeReport) compareRequests(before, after *EndpointSchemas) {
+	switch {
+	case before.Request == nil && after.Request == nil:
+		return
+	case before.Request == nil:
+		r.add(before, "request", "", "now takes a JSON body", true)
+		return
+	case after.Request == nil:
+		r.add(before, "request", "", "no longer takes a JSON body", false)
+		return
+	}
+
+	for _, c := range jsonschema.Diff(before.Request, after.Request) {
+		switch c.Kind {
+		case jsonschema.PropertyAdded:
+			if c.Required {
+				r.add(before, "request", c.Path, fmt.Sprintf("new required field of type %s", strings.Join(c.New, "|")), true)
+			} else {
+				r.add(before, "request", c.Path, fmt.Sprintf("new optional field of type %s", strings.Join(c.New, "|")), false)
+			}
+		case jsonschema.PropertyRemoved:
+			r.add(before, "request", c.Path, "field no longer sent", false)
+		case jsonschema.TypeChanged:
+			r.add(before, "request", c.Path, describeRetype(c), !subsetOfTypes(c.Old, c.New))
+		case jsonschema.FormatChanged:
+			r.add(before, "request", c.Path, describeFormat(c), len(c.New) > 0)
+		case jsonschema.BecameRequired:
+			r.add(before, "request", c.Path, "field became required", true)
+		case jsonschema.BecameOptional:
+			r.add(before, "request", c.Path, "field became optional", false)
+		}
+	}
+}
+
+// compareResponses reports changes to a response body. Fields that clients may rely on
+// being there, or being of a type, are breaking
+func (r *ChangeReport) compareResponses(e *EndpointSchemas, status int, before, after *jsonschema.Schema) {
+	where := fmt.Sprintf("response %d", status)
+
+	for _, c := range jsonschema.Diff(before, after) {
+		switch c.Kind {
+		case jsonschema.PropertyAdded:
+			r.add(e, where, c.Path, fmt.Sprintf("new field of type %s", strings.Join(c.New, "|")), false)
+		case jsonschema.PropertyRemoved:
+			r.add(e, where, c.Path, "field removed", true)
+		case jsonschema.TypeChanged:
+			r.add(e, where, c.Path, describeRetype(c), !subsetOfTypes(c.New, c.Old))
+		case jsonschema.FormatChanged:
+			r.add(e, where, c.Path, describeFormat(c), len(c.Old) > 0)
+		case jsonschema.BecameRequired:
+			r.add(e, where, c.Path, "field is now always present", false)
+		case jsonschema.BecameOptional:
+			r.add(e, where, c.Path, "field is no longer always present", true)
+		}
+	}
+}
+
+// Breaking returns the breaking changes
+func (r *ChangeReport) Breaking() []APIChange {
+	breaking := []APIChange{}
+	for _, c := range r.Changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+
+	return breaking
+}
+
+// String formats the report as one change per line followed by counts
+func (r *ChangeReport) String() string {
+	buf := &bytes.Buffer{}
+
+	for _, c := range r.Changes {
+		fmt.Fprintln(buf, c.String())
+	}
+
+	breaking := len(r.Breaking())
+	fmt.Fprintf(buf, "%d change(s), %d breaking, %d non-breaking\n", len(r.Changes), breaking, len(r.Changes)-breaking)
+
+	return buf.String()
+}
+
+// WriteJSON writes the report as JSON to path
+func (r *ChangeReport) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal change report")
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write change report")
+	}
+
+	return nil
+}
+
+func describeRetype(c jsonschema.Change) string {
+	return fmt.Sprintf("type changed from %s to %s", strings.Join(c.Old, "|"), strings.Join(c.New, "|"))
+}
+
+func describeFormat(c jsonschema.Change) string {
+	from, to := "none", "none"
+	if len(c.Old) > 0 {
+		from = c.Old[0]
+	}
+
+	if len(c.New) > 0 {
+		to = c.New[0]
+	}
+
+	return fmt.Sprintf("format changed from %s to %s", from, to)
+}
+
+// subsetOfTypes returns true if every type in types is allowed by allowed, where number allows integer
+func subsetOfTypes(types, allowed []string) bool {
+	for _, t := range types {
+		if containsString(allowed, t) || (t == "integer" && containsString(allowed, "number")) {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// statusDifference returns the statuses in a that aren't in b
+func statusDifference(a, b []int) []int {
+	diff := []int{}
+	for _, status := range a {
+		if !containsInt(b, status) {
+			diff = append(diff, status)
+		}
+	}
+
+	return diff
+}
+
+func successStatuses(statuses []int) []int {
+	success := []int{}
+	for _, status := range statuses {
+		if status >= 200 && status < 300 {
+			success = append(success, status)
+		}
+	}
+
+	return success
+}
+
+func containsInt(values []int, n int) bool {
+	for _, v := range values {
+		if v == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+func joinInts(values []int) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = fmt.Sprint(v)
+	}
+
+	return strings.Join(strs, "/")
+}
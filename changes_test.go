@@ -0,0 +1,105 @@
+package gopherman
+
+import (
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorded returns an item like those RequestRecorder writes
+func recorded(method, url, body string, status int, response string) postman.CollectionItem {
+	return postman.CollectionItem{
+		Name:     method + " " + url,
+		Request:  postman.Request{Method: method, URL: postman.URL{Raw: url}, Body: postman.Body{Mode: "raw", Raw: body}},
+		Response: []postman.Response{{Mode: "raw", Status: status, Raw: response}},
+	}
+}
+
+func TestDetectChanges(t *testing.T) {
+	before := postman.NewCollection("before", []postman.CollectionItem{
+		recorded("PUT", "/items/1", `{"a":1,"b":1}`, 200, `{"a":1,"b":1}`),
+		recorded("PUT", "/items/2", `{"a":2,"b":2}`, 200, `{"a":2,"b":2}`),
+		recorded("POST", "/items", `{"name":"x"}`, 201, `{"id":1,"created":"2018-01-02T03:04:05Z"}`),
+		recorded("DELETE", "/items/1", "", 204, ""),
+		recorded("GET", "/users", "", 200, `[]`),
+	}, nil)
+
+	after := postman.NewCollection("after", []postman.CollectionItem{
+		recorded("PUT", "/items/1", `{"a":1.5}`, 200, `{"a":1.5}`),
+		recorded("PUT", "/items/2", `{"a":2,"b":2}`, 200, `{"a":2,"b":2}`),
+		recorded("POST", "/items", `{"name":"x","owner":"y"}`, 201, `{"id":1,"created":"2018-01-02","tags":[]}`),
+		recorded("DELETE", "/items/1", "", 200, ""),
+		recorded("DELETE", "/items/2", "", 404, ""),
+	}, nil)
+
+	extra := postman.NewCollection("extra", []postman.CollectionItem{
+		recorded("GET", "/users/search?q=a", "", 200, `[]`),
+	}, nil)
+
+	report := DetectChanges([]*postman.Collection{before}, []*postman.Collection{after, extra})
+
+	changes := []string{}
+	for _, c := range report.Changes {
+		changes = append(changes, c.String())
+	}
+
+	// the same change to a field is breaking in a response but not in a request, or the other way around
+	assert.Equal(t, []string{
+		"non-breaking PUT /items/{id} request $.a: type changed from integer to number",
+		"non-breaking PUT /items/{id} request $.b: field became optional",
+		"BREAKING PUT /items/{id} response 200 $.a: type changed from integer to number",
+		"BREAKING PUT /items/{id} response 200 $.b: field is no longer always present",
+		"BREAKING POST /items request $.owner: new required field of type string",
+		"BREAKING POST /items response 201 $.created: format changed from date-time to date",
+		"non-breaking POST /items response 201 $.tags: new field of type array",
+		"BREAKING DELETE /items/{id} status: success status 204 became 200",
+		"non-breaking DELETE /items/{id} status: 404 newly returned",
+		"BREAKING GET /users endpoint: removed",
+		"non-breaking GET /users/search endpoint: added",
+	}, changes)
+
+	assert.Len(t, report.Breaking(), 6)
+	assert.Contains(t, report.String(), "11 change(s), 6 breaking, 5 non-breaking\n")
+}
+
+func TestDetectChangesBodies(t *testing.T) {
+	cases := []struct {
+		name    string
+		before  string
+		after   string
+		changes []string
+	}{
+		{"unchanged", `{"a":1}`, `{"a":2}`, []string{}},
+		{"body added", ``, `{"a":1}`, []string{
+			"BREAKING POST /x request: now takes a JSON body",
+		}},
+		{"body removed", `{"a":1}`, ``, []string{
+			"non-breaking POST /x request: no longer takes a JSON body",
+		}},
+		{"field removed", `{"a":1,"b":1}`, `{"a":1}`, []string{
+			"non-breaking POST /x request $.b: field no longer sent",
+			"BREAKING POST /x response 200 $.b: field removed",
+		}},
+		{"type narrowed", `{"a":1.5}`, `{"a":1}`, []string{
+			"BREAKING POST /x request $.a: type changed from number to integer",
+			"non-breaking POST /x response 200 $.a: type changed from number to integer",
+		}},
+		{"format added", `{"a":"x"}`, `{"a":"2018-01-02"}`, []string{
+			"BREAKING POST /x request $.a: format changed from none to date",
+			"non-breaking POST /x response 200 $.a: format changed from none to date",
+		}},
+	}
+
+	for _, tc := range cases {
+		before := postman.NewCollection("before", []postman.CollectionItem{recorded("POST", "/x", tc.before, 200, tc.before)}, nil)
+		after := postman.NewCollection("after", []postman.CollectionItem{recorded("POST", "/x", tc.after, 200, tc.after)}, nil)
+
+		changes := []string{}
+		for _, c := range DetectChanges([]*postman.Collection{before}, []*postman.Collection{after}).Changes {
+			changes = append(changes, c.String())
+		}
+
+		assert.Equal(t, tc.changes, changes, tc.name)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cohix/gopherman"
+)
+
+// identityFlags collects repeated -identity flags
+type identityFlags []gopherman.Identity
+
+func (f *identityFlags) String() string {
+	names := []string{}
+	for _, id := range *f {
+		names = append(names, id.Name)
+	}
+
+	return strings.Join(names, ",")
+}
+
+// Set parses name=level:envfile, where an empty envfile means an anonymous identity
+func (f *identityFlags) Set(value string) error {
+	eq := strings.Index(value, "=")
+	colon := strings.Index(value, ":")
+	if eq < 1 || colon < eq {
+		return fmt.Errorf("identity %q isn't in the form name=level:envfile", value)
+	}
+
+	name, path := value[:eq], value[colon+1:]
+
+	level, err := strconv.Atoi(value[eq+1 : colon])
+	if err != nil {
+		return fmt.Errorf("identity %q has an invalid level", value)
+	}
+
+	if path == "" {
+		*f = append(*f, gopherman.Identity{Name: name, Level: level, Anonymous: true})
+		return nil
+	}
+
+	id, err := gopherman.IdentityFromFile(name, path, level)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, *id)
+
+	return nil
+}
+
+func runAuthz(args []string) error {
+	identities := identityFlags{}
+
+	flags := flag.NewFlagSet("authz", flag.ExitOnError)
+	env := flags.String("env", "", "environment file shared by every identity")
+	baseURL := flags.String("base-url", "", "send requests to this base URL (default the environment's BaseUrl and Port)")
+	owner := flags.String("owner", "", "name of the identity that owns the collection's resources")
+	flags.Var(&identities, "identity", "an identity as name=level:envfile, with an empty envfile for no auth (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gopherman authz [flags] -owner name -identity name=level:envfile... collection...")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || len(identities) < 2 || *owner == "" {
+		flags.Usage()
+		return fmt.Errorf("authz takes an owner, at least two identities and at least one collection")
+	}
+
+	found := false
+	for i := range identities {
+		if identities[i].Name == *owner {
+			identities[i].Owner = true
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("owner %s isn't one of the identities", *owner)
+	}
+
+	tester, err := newTester(*env, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	if *baseURL != "" {
+		tester.Target = &gopherman.Target{BaseURL: *baseURL}
+	}
+
+	matrix, err := tester.AuthorizationMatrix(context.Background(), identities)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(tester.Secrets.Mask(matrix.String()))
+
+	if len(matrix.Violations) == 0 {
+		return nil
+	}
+
+	for _, v := range matrix.Violations {
+		fmt.Println(v.String())
+	}
+
+	return fmt.Errorf("%d violation(s)", len(matrix.Violations))
+}
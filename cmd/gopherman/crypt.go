@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/cohix/gopherman/postman"
+)
+
+func runEncrypt(args []string) error {
+	in, out, passphrase, err := cryptArgs("encrypt", "default overwrite the input", args)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		out = in
+	}
+
+	if err := postman.EncryptFile(in, out, passphrase); err != nil {
+		return err
+	}
+
+	fmt.Printf("encrypted %s to %s\n", in, out)
+
+	return nil
+}
+
+// runDecrypt writes to stdout unless -o is given, so that a wrong key can't replace the encrypted file
+func runDecrypt(args []string) error {
+	in, out, passphrase, err := cryptArgs("decrypt", "default stdout", args)
+	if err != nil {
+		return err
+	}
+
+	if out != "" {
+		if err := postman.DecryptFile(in, out, passphrase); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "decrypted %s to %s\n", in, out)
+
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+
+	plain, err := postman.Decrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(plain)
+
+	return err
+}
+
+// cryptArgs parses the flags shared by encrypt and decrypt, returning the input and output files and the passphrase
+func cryptArgs(name, outDefault string, args []string) (string, string, []byte, error) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	keyFile := flags.String("key-file", "", "read the passphrase from this file (default $"+postman.PassphraseEnvVar+" or $"+postman.KeyFileEnvVar+")")
+	out := flags.String("o", "", "write the output to this file ("+outDefault+")")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: gopherman %s [-key-file file] [-o output] file\n", name)
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return "", "", nil, fmt.Errorf("%s takes exactly one file", name)
+	}
+
+	passphrase, err := passphrase(*keyFile)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return flags.Arg(0), *out, passphrase, nil
+}
+
+func passphrase(keyFile string) ([]byte, error) {
+	if keyFile != "" {
+		return postman.KeyFromFile(keyFile)
+	}
+
+	return postman.PassphraseFromEnv()
+}
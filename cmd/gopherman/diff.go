@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/cohix/gopherman"
+	"github.com/cohix/gopherman/postman"
+)
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	before := flags.String("before", "", "comma separated collections of the current version")
+	after := flags.String("after", "", "comma separated collections of the new version")
+	out := flags.String("o", "", "write the report as JSON to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gopherman diff [-o file] -before a.json[,b.json] -after c.json[,d.json]")
+		fmt.Fprintln(flags.Output(), "       gopherman diff [-o file] before-collection after-collection")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	beforePaths, afterPaths := []string{}, []string{}
+
+	if *before != "" {
+		beforePaths = strings.Split(*before, ",")
+	}
+
+	if *after != "" {
+		afterPaths = strings.Split(*after, ",")
+	}
+
+	switch {
+	case len(beforePaths) == 0 && len(afterPaths) == 0 && flags.NArg() == 2:
+		beforePaths, afterPaths = flags.Args()[:1], flags.Args()[1:]
+	case len(beforePaths) == 0 || len(afterPaths) == 0 || flags.NArg() > 0:
+		flags.Usage()
+		return fmt.Errorf("diff takes -before and -after collections, or exactly two collections")
+	}
+
+	beforeCollections, err := loadCollections(beforePaths)
+	if err != nil {
+		return err
+	}
+
+	afterCollections, err := loadCollections(afterPaths)
+	if err != nil {
+		return err
+	}
+
+	report := gopherman.DetectChanges(beforeCollections, afterCollections)
+
+	fmt.Print(report.String())
+
+	if *out != "" {
+		if err := report.WriteJSON(*out); err != nil {
+			return err
+		}
+	}
+
+	if breaking := len(report.Breaking()); breaking > 0 {
+		return fmt.Errorf("%d breaking change(s)", breaking)
+	}
+
+	return nil
+}
+
+// loadCollections loads each of the collection files at paths
+func loadCollections(paths []string) ([]*postman.Collection, error) {
+	collections := []*postman.Collection{}
+
+	for _, path := range paths {
+		collection, err := postman.CollectionFromFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		collections = append(collections, collection)
+	}
+
+	return collections, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/cohix/gopherman"
+)
+
+func runFuzz(args []string) error {
+	flags := flag.NewFlagSet("fuzz", flag.ExitOnError)
+	env := flags.String("env", "", "environment file")
+	baseURL := flags.String("base-url", "", "send requests to this base URL (default the environment's BaseUrl and Port)")
+	items := flags.String("items", "", "comma separated names of the items to fuzz (default every item)")
+	mutations := flags.Int("n", 0, "most mutations to send per item, chosen at random (default all)")
+	seed := flags.Int64("seed", 0, "random seed for choosing mutations")
+	timeout := flags.Duration("timeout", 0, "how long a request may take before it's a finding (default 10s)")
+	out := flags.String("o", "fuzz-findings.json", "write a collection reproducing the findings to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gopherman fuzz [flags] collection...")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("fuzz takes at least one collection")
+	}
+
+	tester, err := newTester(*env, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	if *baseURL != "" {
+		tester.Target = &gopherman.Target{BaseURL: *baseURL}
+	}
+
+	opts := gopherman.FuzzOptions{
+		Mutations: *mutations,
+		Seed:      *seed,
+		Timeout:   *timeout,
+		Output:    *out,
+	}
+
+	if *items != "" {
+		opts.Items = strings.Split(*items, ",")
+	}
+
+	report, err := tester.Fuzz(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("fuzzed %d item(s) with %d request(s)\n", report.Items, report.Requests)
+
+	if len(report.Findings) == 0 {
+		return nil
+	}
+
+	for i := range report.Findings {
+		fmt.Println(tester.Secrets.Mask(report.Findings[i].String()))
+	}
+
+	fmt.Printf("wrote reproducing requests to %s\n", *out)
+
+	return fmt.Errorf("%d finding(s)", len(report.Findings))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cohix/gopherman"
+	"github.com/cohix/gopherman/postman"
+)
+
+func runInfer(args []string) error {
+	flags := flag.NewFlagSet("infer", flag.ExitOnError)
+	out := flags.String("o", ".", "write the schemas to this directory")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gopherman infer [-o dir] collection...")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("infer takes at least one collection")
+	}
+
+	tester, err := newTester("", flags.Args())
+	if err != nil {
+		return err
+	}
+
+	collections := []*postman.Collection{}
+	for i := range tester.Collections {
+		collections = append(collections, &tester.Collections[i])
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+
+	endpoints := gopherman.InferSchemas(collections...)
+
+	written, err := gopherman.WriteSchemas(*out, endpoints)
+	for _, path := range written {
+		fmt.Println("wrote " + path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("inferred schemas for %d endpoint(s)\n", len(endpoints))
+
+	return nil
+}
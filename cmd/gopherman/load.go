@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cohix/gopherman"
+)
+
+func runLoad(args []string) error {
+	flags := flag.NewFlagSet("load", flag.ExitOnError)
+	env := flags.String("env", "", "environment file")
+	baseURL := flags.String("base-url", "", "send requests to this base URL (default the environment's BaseUrl and Port)")
+	items := flags.String("items", "", "comma separated names of the items to send (default every item, in order)")
+	concurrency := flags.Int("c", 1, "number of concurrent workers")
+	duration := flags.Duration("d", 0, "how long to run for")
+	requests := flags.Int("n", 0, "how many requests to send in total")
+	rate := flags.Float64("rate", 0, "most requests per second (default unlimited)")
+	timeout := flags.Duration("timeout", 0, "timeout for each request")
+	p50 := flags.Duration("p50", 0, "fail if any item's p50 latency exceeds this")
+	p90 := flags.Duration("p90", 0, "fail if any item's p90 latency exceeds this")
+	p99 := flags.Duration("p99", 0, "fail if any item's p99 latency exceeds this")
+	errorRate := flags.Float64("max-errors", 0, "fail if any item's error rate exceeds this percentage, 0 for no errors (default unchecked)")
+	throughput := flags.Float64("min-rate", 0, "fail if the total requests per second is below this")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gopherman load [flags] collection...")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("load takes at least one collection")
+	}
+
+	if *duration == 0 && *requests == 0 {
+		*duration = 10 * time.Second
+	}
+
+	tester, err := newTester(*env, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	if *baseURL != "" {
+		tester.Target = &gopherman.Target{BaseURL: *baseURL}
+	}
+
+	tester.Timeout = *timeout
+
+	opts := gopherman.LoadOptions{
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Requests:    *requests,
+		Rate:        *rate,
+		Thresholds: gopherman.LoadThresholds{
+			P50:        *p50,
+			P90:        *p90,
+			P99:        *p99,
+			Throughput: *throughput,
+		},
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "max-errors" {
+			rate := *errorRate / 100
+			opts.Thresholds.ErrorRate = &rate
+		}
+	})
+
+	if *items != "" {
+		opts.Items = strings.Split(*items, ",")
+	}
+
+	report, err := tester.Load(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report.String())
+
+	if errs := report.Check(opts.Thresholds); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		return fmt.Errorf("%d threshold(s) exceeded", len(errs))
+	}
+
+	return nil
+}
+
+// newTester loads collections with an optional environment file, both relative to the working directory
+func newTester(env string, collections []string) (*gopherman.Tester, error) {
+	return gopherman.NewTesterWithCollection("", env, collections...)
+}
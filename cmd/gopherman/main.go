@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is a gopherman subcommand, run with the arguments that follow its name
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"authz":   {usage: "replay a collection as several identities and flag access control failures", run: runAuthz},
+	"encrypt": {usage: "encrypt a file (such as an environment) for committing", run: runEncrypt},
+	"decrypt": {usage: "decrypt a file encrypted with gopherman encrypt", run: runDecrypt},
+	"diff":    {usage: "report API changes between two recorded collections, failing on breaking ones", run: runDiff},
+	"fuzz":    {usage: "send mutated copies of a collection's requests to find server crashes", run: runFuzz},
+	"infer":   {usage: "infer JSON Schemas for each endpoint from recorded collections", run: runInfer},
+	"load":    {usage: "send a collection's requests repeatedly and report latency percentiles", run: runLoad},
+	"shadow":  {usage: "replay a collection against two servers and report where their responses differ", run: runShadow},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %s\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "gopherman: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gopherman <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/cohix/gopherman"
+)
+
+func runShadow(args []string) error {
+	flags := flag.NewFlagSet("shadow", flag.ExitOnError)
+	env := flags.String("env", "", "environment file")
+	base := flags.String("base", "", "base URL of the current server")
+	candidate := flags.String("candidate", "", "base URL of the server to compare with it")
+	items := flags.String("items", "", "comma separated names of the items to replay (default every item)")
+	ignore := flags.String("ignore", "", "comma separated JSON paths to leave out of body comparisons, such as $..id")
+	headers := flags.Bool("headers", false, "compare response headers too")
+	ignoreHeaders := flags.String("ignore-headers", "", "comma separated headers to leave out of header comparisons")
+	out := flags.String("o", "", "write the report as JSON to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gopherman shadow [flags] -base url -candidate url collection...")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || *base == "" || *candidate == "" {
+		flags.Usage()
+		return fmt.Errorf("shadow takes a base, a candidate and at least one collection")
+	}
+
+	tester, err := newTester(*env, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	opts := gopherman.ShadowOptions{
+		Comparator:     gopherman.NewComparator(),
+		CompareHeaders: *headers,
+	}
+
+	if *items != "" {
+		opts.Items = strings.Split(*items, ",")
+	}
+
+	if *ignore != "" {
+		opts.Comparator.Ignore(strings.Split(*ignore, ",")...)
+	}
+
+	if *ignoreHeaders != "" {
+		opts.IgnoreHeaders = strings.Split(*ignoreHeaders, ",")
+	}
+
+	report, err := tester.Shadow(context.Background(), gopherman.ShadowURL("base", *base), gopherman.ShadowURL("candidate", *candidate), opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report.String())
+
+	if *out != "" {
+		if err := report.WriteJSON(*out); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Diffs) > 0 {
+		return fmt.Errorf("%d item(s) differ", len(report.Diffs))
+	}
+
+	return nil
+}
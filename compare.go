@@ -0,0 +1,417 @@
+package gopherman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Matcher decides whether an actual JSON value is acceptable in place of the expected one,
+// returning an error describing the mismatch if not
+type Matcher interface {
+	Match(expected, actual interface{}) error
+}
+
+// MatcherFunc adapts a function to a Matcher
+type MatcherFunc func(expected, actual interface{}) error
+
+// Match calls f
+func (f MatcherFunc) Match(expected, actual interface{}) error {
+	return f(expected, actual)
+}
+
+// AnyValue matches any actual value, as long as one is present
+func AnyValue() Matcher {
+	return MatcherFunc(func(expected, actual interface{}) error {
+		return nil
+	})
+}
+
+// TypeOnly matches an actual value of the same JSON type as the expected one
+func TypeOnly() Matcher {
+	return MatcherFunc(func(expected, actual interface{}) error {
+		if jsonType(expected) != jsonType(actual) {
+			return fmt.Errorf("expected a %s, got %s %s", jsonType(expected), jsonType(actual), jsonString(actual))
+		}
+
+		return nil
+	})
+}
+
+// Regex matches an actual string, or the JSON text of any other value, against pattern
+func Regex(pattern string) Matcher {
+	re, err := regexp.Compile(pattern)
+
+	return MatcherFunc(func(expected, actual interface{}) error {
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s: %s", pattern, err)
+		}
+
+		str, ok := actual.(string)
+		if !ok {
+			str = jsonString(actual)
+		}
+
+		if !re.MatchString(str) {
+			return fmt.Errorf("expected a value matching %s, got %s", pattern, jsonString(actual))
+		}
+
+		return nil
+	})
+}
+
+// Tolerance matches an actual number within delta of the expected number
+func Tolerance(delta float64) Matcher {
+	return MatcherFunc(func(expected, actual interface{}) error {
+		exp, ok := expected.(float64)
+		if !ok {
+			return fmt.Errorf("expected value %s is not a number", jsonString(expected))
+		}
+
+		act, ok := actual.(float64)
+		if !ok {
+			return fmt.Errorf("expected a number, got %s %s", jsonType(actual), jsonString(actual))
+		}
+
+		if math.Abs(exp-act) > delta {
+			return fmt.Errorf("expected %v ± %v, got %v", exp, delta, act)
+		}
+
+		return nil
+	})
+}
+
+// unorderedMatcher marks an array path as order-insensitive; the Comparator handles it itself
+// so that the array's elements are still compared using the rest of its rules
+type unorderedMatcher struct{}
+
+func (unorderedMatcher) Match(expected, actual interface{}) error {
+	return nil
+}
+
+// Unordered matches arrays containing the same elements in any order
+func Unordered() Matcher {
+	return unorderedMatcher{}
+}
+
+// Difference is one mismatch between an expected and actual JSON document
+type Difference struct {
+	Path    string
+	Message string
+}
+
+func (d Difference) Error() string {
+	return fmt.Sprintf("%s: %s", d.Path, d.Message)
+}
+
+// FormatDifferences renders differences as a path-by-path diff, one per line
+func FormatDifferences(diffs []Difference) string {
+	lines := make([]string, len(diffs))
+	for i, d := range diffs {
+		lines[i] = d.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Comparator compares JSON documents structurally, so key order and whitespace don't matter.
+// Paths use the JSON path syntax of $.items[0].id, including the wildcards .*, [*] and ..
+type Comparator struct {
+	ignore   [][]pathSegment
+	matchers []pathMatcher
+	errs     []Difference
+}
+
+type pathMatcher struct {
+	pattern []pathSegment
+	matcher Matcher
+}
+
+// NewComparator returns a Comparator with no ignore rules or matchers
+func NewComparator() *Comparator {
+	return &Comparator{}
+}
+
+// Ignore skips the values at paths entirely, including when they are missing
+func (c *Comparator) Ignore(paths ...string) *Comparator {
+	for _, path := range paths {
+		pattern, err := parsePath(path)
+		if err != nil {
+			c.errs = append(c.errs, Difference{Path: path, Message: err.Error()})
+			continue
+		}
+
+		c.ignore = append(c.ignore, pattern)
+	}
+
+	return c
+}
+
+// Match compares the values at path using matcher instead of by equality
+func (c *Comparator) Match(path string, matcher Matcher) *Comparator {
+	pattern, err := parsePath(path)
+	if err != nil {
+		c.errs = append(c.errs, Difference{Path: path, Message: err.Error()})
+		return c
+	}
+
+	c.matchers = append(c.matchers, pathMatcher{pattern: pattern, matcher: matcher})
+
+	return c
+}
+
+// Compare compares two bodies. If both are JSON they are compared structurally,
+// otherwise they are compared as text with surrounding whitespace trimmed
+func (c *Comparator) Compare(expected, actual []byte) []Difference {
+	var expectedJSON, actualJSON interface{}
+
+	expectedErr := json.Unmarshal(expected, &expectedJSON)
+	actualErr := json.Unmarshal(actual, &actualJSON)
+
+	if expectedErr != nil || actualErr != nil {
+		// copied so that callers, possibly in parallel, never share the comparator's own slice
+		diffs := append([]Difference{}, c.errs...)
+
+		if expectedErr == nil {
+			return append(diffs, Difference{Path: "$", Message: fmt.Sprintf("expected JSON, got %s", truncate(string(actual)))})
+		}
+
+		if strings.TrimSpace(string(expected)) != strings.TrimSpace(string(actual)) {
+			return append(diffs, Difference{Path: "$", Message: fmt.Sprintf("expected body %s, got %s", truncate(string(expected)), truncate(string(actual)))})
+		}
+
+		return diffs
+	}
+
+	return c.CompareValues(expectedJSON, actualJSON)
+}
+
+// CompareValues compares two decoded JSON values
+func (c *Comparator) CompareValues(expected, actual interface{}) []Difference {
+	diffs := append([]Difference{}, c.errs...)
+
+	return c.compare(diffs, nil, expected, actual)
+}
+
+func (c *Comparator) ignored(path []pathSegment) bool {
+	for _, pattern := range c.ignore {
+		if matchPath(pattern, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Comparator) matcherFor(path []pathSegment) Matcher {
+	// the last matching rule wins, so general rules can be refined by later, more specific ones
+	for i := len(c.matchers) - 1; i >= 0; i-- {
+		if matchPath(c.matchers[i].pattern, path) {
+			return c.matchers[i].matcher
+		}
+	}
+
+	return nil
+}
+
+func (c *Comparator) compare(diffs []Difference, path []pathSegment, expected, actual interface{}) []Difference {
+	if c.ignored(path) {
+		return diffs
+	}
+
+	matcher := c.matcherFor(path)
+	if matcher != nil {
+		if _, unordered := matcher.(unorderedMatcher); !unordered {
+			if err := matcher.Match(expected, actual); err != nil {
+				diffs = append(diffs, Difference{Path: formatPath(path), Message: err.Error()})
+			}
+
+			return diffs
+		}
+	}
+
+	if jsonType(expected) != jsonType(actual) {
+		return append(diffs, Difference{
+			Path:    formatPath(path),
+			Message: fmt.Sprintf("expected %s %s, got %s %s", jsonType(expected), jsonString(expected), jsonType(actual), jsonString(actual)),
+		})
+	}
+
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		return c.compareObjects(diffs, path, exp, actual.(map[string]interface{}))
+	case []interface{}:
+		if matcher != nil {
+			return c.compareUnordered(diffs, path, exp, actual.([]interface{}))
+		}
+
+		return c.compareArrays(diffs, path, exp, actual.([]interface{}))
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		diffs = append(diffs, Difference{
+			Path:    formatPath(path),
+			Message: fmt.Sprintf("expected %s, got %s", jsonString(expected), jsonString(actual)),
+		})
+	}
+
+	return diffs
+}
+
+func (c *Comparator) compareObjects(diffs []Difference, path []pathSegment, expected, actual map[string]interface{}) []Difference {
+	keys := []string{}
+	for k := range expected {
+		keys = append(keys, k)
+	}
+
+	for k := range actual {
+		if _, ok := expected[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child := appendPath(path, keySeg(k))
+		if c.ignored(child) {
+			continue
+		}
+
+		exp, inExpected := expected[k]
+		act, inActual := actual[k]
+
+		switch {
+		case !inActual:
+			diffs = append(diffs, Difference{Path: formatPath(child), Message: fmt.Sprintf("missing, expected %s", jsonString(exp))})
+		case !inExpected:
+			diffs = append(diffs, Difference{Path: formatPath(child), Message: fmt.Sprintf("unexpected value %s", jsonString(act))})
+		default:
+			diffs = c.compare(diffs, child, exp, act)
+		}
+	}
+
+	return diffs
+}
+
+func (c *Comparator) compareArrays(diffs []Difference, path []pathSegment, expected, actual []interface{}) []Difference {
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		child := appendPath(path, indexSeg(i))
+		if c.ignored(child) {
+			continue
+		}
+
+		switch {
+		case i >= len(actual):
+			diffs = append(diffs, Difference{Path: formatPath(child), Message: fmt.Sprintf("missing, expected %s", jsonString(expected[i]))})
+		case i >= len(expected):
+			diffs = append(diffs, Difference{Path: formatPath(child), Message: fmt.Sprintf("unexpected value %s", jsonString(actual[i]))})
+		default:
+			diffs = c.compare(diffs, child, expected[i], actual[i])
+		}
+	}
+
+	return diffs
+}
+
+// compareUnordered pairs each expected element with the first unused actual element that matches it
+func (c *Comparator) compareUnordered(diffs []Difference, path []pathSegment, expected, actual []interface{}) []Difference {
+	used := make([]bool, len(actual))
+
+	for i, exp := range expected {
+		found := false
+
+		for j, act := range actual {
+			if used[j] {
+				continue
+			}
+
+			// elements are compared as if they were at the expected index, so [*] rules apply
+			if len(c.compare(nil, appendPath(path, indexSeg(i)), exp, act)) == 0 {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			diffs = append(diffs, Difference{
+				Path:    formatPath(appendPath(path, indexSeg(i))),
+				Message: fmt.Sprintf("no matching element for %s", jsonString(exp)),
+			})
+		}
+	}
+
+	for j, act := range actual {
+		if !used[j] {
+			diffs = append(diffs, Difference{
+				Path:    formatPath(appendPath(path, indexSeg(j))),
+				Message: fmt.Sprintf("unexpected element %s", jsonString(act)),
+			})
+		}
+	}
+
+	return diffs
+}
+
+func appendPath(path []pathSegment, segment pathSegment) []pathSegment {
+	child := make([]pathSegment, len(path), len(path)+1)
+	copy(child, path)
+
+	return append(child, segment)
+}
+
+// jsonType returns the JSON type name of a decoded value
+func jsonType(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", val)
+}
+
+func jsonString(val interface{}) string {
+	buf := &bytes.Buffer{}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(val); err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+
+	return truncate(strings.TrimSpace(buf.String()))
+}
+
+// truncate shortens str to at most 200 bytes for a message, without splitting a UTF-8 character
+func truncate(str string) string {
+	const max = 200
+
+	if len(str) <= max {
+		return str
+	}
+
+	cut := max
+	for cut > 0 && !utf8.RuneStart(str[cut]) {
+		cut--
+	}
+
+	return str[:cut] + "..."
+}
@@ -0,0 +1,111 @@
+package gopherman
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparatorCompare(t *testing.T) {
+	tests := []struct {
+		name       string
+		comparator *Comparator
+		expected   string
+		actual     string
+		diffs      []string
+	}{
+		{"equal", NewComparator(), `{"a":1}`, `{"a":1}`, nil},
+		{"key order and whitespace", NewComparator(), `{"a":1,"b":[1,2]}`, "{ \"b\": [1, 2],\n \"a\": 1 }", nil},
+		{"changed value", NewComparator(), `{"a":1}`, `{"a":2}`, []string{"$.a: expected 1, got 2"}},
+		{"changed type", NewComparator(), `{"a":1}`, `{"a":"1"}`, []string{`$.a: expected number 1, got string "1"`}},
+		{"missing key", NewComparator(), `{"a":1,"b":2}`, `{"a":1}`, []string{"$.b: missing, expected 2"}},
+		{"extra key", NewComparator(), `{"a":1}`, `{"a":1,"b":2}`, []string{"$.b: unexpected value 2"}},
+		{"nested", NewComparator(), `{"a":{"b":[{"c":1}]}}`, `{"a":{"b":[{"c":2}]}}`, []string{"$.a.b[0].c: expected 1, got 2"}},
+		{"array length", NewComparator(), `[1,2]`, `[1]`, []string{"$[1]: missing, expected 2"}},
+		{"array order", NewComparator(), `[1,2]`, `[2,1]`, []string{"$[0]: expected 1, got 2", "$[1]: expected 2, got 1"}},
+		{"quoted key", NewComparator(), `{"a b":1}`, `{"a b":2}`, []string{"$['a b']: expected 1, got 2"}},
+		{"html is not escaped", NewComparator(), `{"a":"<b>"}`, `{"a":"&"}`, []string{`$.a: expected "<b>", got "&"`}},
+
+		{"ignore", NewComparator().Ignore("$.id"), `{"id":1,"a":1}`, `{"id":2,"a":1}`, nil},
+		{"ignore missing", NewComparator().Ignore("$.id"), `{"id":1}`, `{}`, nil},
+		{"ignore descendants", NewComparator().Ignore("$..createdAt"), `{"createdAt":1,"items":[{"createdAt":1}]}`, `{"createdAt":2,"items":[{"createdAt":3}]}`, nil},
+		{"ignore wildcard index", NewComparator().Ignore("$.items[*].id"), `{"items":[{"id":1},{"id":2}]}`, `{"items":[{"id":3},{"id":4}]}`, nil},
+		{"ignore wildcard key", NewComparator().Ignore("$.*.id"), `{"a":{"id":1},"b":{"id":1}}`, `{"a":{"id":2},"b":{"id":3}}`, nil},
+
+		{"any value", NewComparator().Match("$.id", AnyValue()), `{"id":1}`, `{"id":"x"}`, nil},
+		{"any value missing", NewComparator().Match("$.id", AnyValue()), `{"id":1}`, `{}`, []string{"$.id: missing, expected 1"}},
+		{"type only", NewComparator().Match("$.id", TypeOnly()), `{"id":1}`, `{"id":2}`, nil},
+		{"type only mismatch", NewComparator().Match("$.id", TypeOnly()), `{"id":1}`, `{"id":"2"}`, []string{`$.id: expected a number, got string "2"`}},
+		{"regex", NewComparator().Match("$.at", Regex(`^\d{4}-`)), `{"at":"2020-01-01"}`, `{"at":"2024-06-30"}`, nil},
+		{"regex mismatch", NewComparator().Match("$.at", Regex(`^\d{4}-`)), `{"at":"2020-01-01"}`, `{"at":"soon"}`, []string{`$.at: expected a value matching ^\d{4}-, got "soon"`}},
+		{"tolerance", NewComparator().Match("$.n", Tolerance(0.5)), `{"n":1}`, `{"n":1.4}`, nil},
+		{"tolerance exceeded", NewComparator().Match("$.n", Tolerance(0.5)), `{"n":1}`, `{"n":2}`, []string{"$.n: expected 1 ± 0.5, got 2"}},
+		{"unordered", NewComparator().Match("$.tags", Unordered()), `{"tags":["a","b"]}`, `{"tags":["b","a"]}`, nil},
+		{"unordered with rules", NewComparator().Match("$.items", Unordered()).Ignore("$.items[*].id"), `{"items":[{"id":1,"n":"a"},{"id":2,"n":"b"}]}`, `{"items":[{"id":9,"n":"b"},{"id":8,"n":"a"}]}`, nil},
+		{"unordered mismatch", NewComparator().Match("$", Unordered()), `["a","b"]`, `["b","c"]`, []string{`$[0]: no matching element for "a"`, `$[1]: unexpected element "c"`}},
+		{"later matcher wins", NewComparator().Match("$..id", AnyValue()).Match("$.user.id", TypeOnly()), `{"id":1,"user":{"id":1}}`, `{"id":"x","user":{"id":"y"}}`, []string{`$.user.id: expected a number, got string "y"`}},
+
+		{"text", NewComparator(), "ok\n", "ok", nil},
+		{"text mismatch", NewComparator(), "ok", "not ok", []string{"$: expected body ok, got not ok"}},
+		{"expected json", NewComparator(), `{"a":1}`, "oops", []string{"$: expected JSON, got oops"}},
+		{"invalid rule", NewComparator().Ignore("$.a["), `{}`, `{}`, []string{"$.a[: invalid path $.a[: unclosed ["}},
+	}
+
+	for _, test := range tests {
+		diffs := test.comparator.Compare([]byte(test.expected), []byte(test.actual))
+
+		messages := []string{}
+		for _, d := range diffs {
+			messages = append(messages, d.Error())
+		}
+
+		if test.diffs == nil {
+			test.diffs = []string{}
+		}
+
+		assert.Equal(t, test.diffs, messages, test.name)
+	}
+}
+
+func TestComparatorDoesNotShareErrors(t *testing.T) {
+	comparator := NewComparator().Ignore("$.a[")
+
+	first := comparator.Compare([]byte("a"), []byte("b"))
+	first[0].Message = "changed"
+	first = append(first, Difference{Path: "$", Message: "appended"})
+
+	second := comparator.Compare([]byte("a"), []byte("a"))
+	assert.Equal(t, []Difference{{Path: "$.a[", Message: "invalid path $.a[: unclosed ["}}, second)
+
+	// concurrent comparisons with a shared comparator must not race on its configuration errors
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			diffs := comparator.Compare([]byte("x"), []byte("y"))
+			assert.Len(t, diffs, 2)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestTruncate(t *testing.T) {
+	short := strings.Repeat("a", 200)
+	assert.Equal(t, short, truncate(short))
+
+	assert.Equal(t, short+"...", truncate(short+"b"))
+
+	// é is two bytes, and the 200 byte limit falls in the middle of one
+	accented := "a" + strings.Repeat("é", 150)
+	truncated := truncate(accented)
+
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, "a"+strings.Repeat("é", 99)+"...", truncated)
+}
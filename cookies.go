@@ -0,0 +1,118 @@
+package gopherman
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// LoadCookies adds the cookies in a Postman or newman cookie file to t.Cookies,
+// and resets the cookie jar so that they're in it
+func (t *Tester) LoadCookies(path string) error {
+	cookies, err := postman.CookiesFromFile(path)
+	if err != nil {
+		return err
+	}
+
+	t.Cookies = append(t.Cookies, cookies...)
+
+	return t.ResetCookies()
+}
+
+// ResetCookies replaces the cookie jar with a new one holding only t.Cookies.
+// Each run, and each iteration, starts with a reset jar
+func (t *Tester) ResetCookies() error {
+	jar, err := newCookieJar(t.Cookies)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.jar = jar
+	t.mu.Unlock()
+
+	return nil
+}
+
+// newCookieJar returns a cookie jar holding cookies
+func newCookieJar(cookies []postman.Cookie) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cookie jar")
+	}
+
+	for i := range cookies {
+		c := &cookies[i]
+		jar.SetCookies(c.URL(), []*http.Cookie{c.HTTPCookie()})
+	}
+
+	return jar, nil
+}
+
+// Jar returns the cookie jar that requests are sent with, unless t.Client has a jar of its own
+func (t *Tester) Jar() http.CookieJar {
+	t.mu.Lock()
+	jar := t.jar
+	t.mu.Unlock()
+
+	if jar == nil {
+		// a jar without seed cookies can't fail to be created
+		t.ResetCookies()
+
+		t.mu.Lock()
+		jar = t.jar
+		t.mu.Unlock()
+	}
+
+	return jar
+}
+
+// Cookies returns the cookies in the jar that would be sent with the item's request
+func (t *TestHelper) Cookies() []*http.Cookie {
+	if t.jar == nil || t.url == nil {
+		return nil
+	}
+
+	return t.jar.Cookies(t.url)
+}
+
+// Cookie returns the named cookie from Cookies
+func (t *TestHelper) Cookie(name string) (*http.Cookie, bool) {
+	for _, c := range t.Cookies() {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// AssertCookie adds an error unless the jar has the named cookie for the item's request,
+// with the given value unless value is empty
+func (t *TestHelper) AssertCookie(name, value string) bool {
+	c, ok := t.Cookie(name)
+	if !ok {
+		t.Error(fmt.Errorf("expected cookie %s to be set", name))
+		return false
+	}
+
+	if value != "" && c.Value != value {
+		t.Error(fmt.Errorf("expected cookie %s to be %s, got %s", name, value, c.Value))
+		return false
+	}
+
+	return true
+}
+
+// AssertNoCookie adds an error if the jar has the named cookie for the item's request
+func (t *TestHelper) AssertNoCookie(name string) bool {
+	if _, ok := t.Cookie(name); ok {
+		t.Error(fmt.Errorf("expected cookie %s not to be set", name))
+		return false
+	}
+
+	return true
+}
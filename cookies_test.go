@@ -0,0 +1,97 @@
+package gopherman
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+// cookieHandler sets a session cookie on /login, requires it on /me, and counts visits in a cookie on /visit
+func cookieHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/login":
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+	case "/me":
+		if c, err := r.Cookie("session"); err != nil || c.Value != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		pref := ""
+		if c, err := r.Cookie("pref"); err == nil {
+			pref = c.Value
+		}
+
+		fmt.Fprint(w, pref)
+	case "/visit":
+		visits := 0
+		if c, err := r.Cookie("visits"); err == nil {
+			visits, _ = strconv.Atoi(c.Value)
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "visits", Value: strconv.Itoa(visits + 1), Path: "/"})
+		fmt.Fprint(w, visits+1)
+	}
+}
+
+func cookieItem(path string, status int, body string) postman.CollectionItem {
+	return postman.CollectionItem{
+		Name:     path,
+		Request:  postman.Request{Method: http.MethodGet, URL: postman.URL{Raw: "http://localhost" + path}},
+		Response: []postman.Response{{Status: status, Raw: body}},
+	}
+}
+
+func TestRunKeepsCookies(t *testing.T) {
+	dir, file := writeCollection(t, cookieItem("/login", 200, ""), cookieItem("/me", 200, "dark"))
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithHandler(http.HandlerFunc(cookieHandler), dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tester.Cookies = []postman.Cookie{{Name: "pref", Value: "dark", Domain: "localhost", HostOnly: true}}
+
+	checked := 0
+	tester.Handler = func(helper *TestHelper, req *postman.Request, expected *postman.Response, actual *postman.Response) {
+		DefaultHandler(helper, req, expected, actual)
+
+		helper.AssertCookie("session", "abc")
+		helper.AssertCookie("pref", "dark")
+		checked++
+	}
+
+	tester.Run(t)
+
+	assert.Equal(t, 2, checked)
+
+	// the next run starts with only the seed cookies
+	assert.NoError(t, tester.ResetCookies())
+	helper := NewTestHelper(t)
+	helper.jar = tester.Jar()
+	helper.url = (&postman.Cookie{Domain: "localhost"}).URL()
+
+	assert.True(t, helper.AssertNoCookie("session"))
+	assert.True(t, helper.AssertCookie("pref", "dark"))
+}
+
+func TestIterationsResetCookies(t *testing.T) {
+	dir, file := writeCollection(t, cookieItem("/visit", 200, "1"))
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithHandler(http.HandlerFunc(cookieHandler), dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every iteration is a first visit, since the jar is reset in between
+	tester.RunIterations(t, &postman.Data{Fields: []string{"n"}, Rows: []map[string]string{{"n": "1"}, {"n": "2"}, {"n": "3"}}})
+
+	assert.Equal(t, 3, tester.Report().Summary().Passed)
+}
@@ -0,0 +1,56 @@
+package gopherman
+
+import (
+	"fmt"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+var errNoExamples = errors.New("has no example responses to compare against")
+
+// exampleSelector chooses which of an item's examples to test against, returning its index
+type exampleSelector func(examples []postman.Response) (int, error)
+
+// firstExample chooses the first example
+func firstExample(examples []postman.Response) (int, error) {
+	if len(examples) == 0 {
+		return 0, errNoExamples
+	}
+
+	return 0, nil
+}
+
+// exampleNamed chooses the example with a particular name
+func exampleNamed(name string) exampleSelector {
+	return func(examples []postman.Response) (int, error) {
+		if len(examples) == 0 {
+			return 0, errNoExamples
+		}
+
+		for i, ex := range examples {
+			if ex.Name == name {
+				return i, nil
+			}
+		}
+
+		return 0, fmt.Errorf("has no example named %s", name)
+	}
+}
+
+// exampleWithStatus chooses the first example with a particular status code
+func exampleWithStatus(status int) exampleSelector {
+	return func(examples []postman.Response) (int, error) {
+		if len(examples) == 0 {
+			return 0, errNoExamples
+		}
+
+		for i, ex := range examples {
+			if ex.Status == status {
+				return i, nil
+			}
+		}
+
+		return 0, fmt.Errorf("has no example with status %d", status)
+	}
+}
@@ -0,0 +1,109 @@
+package gopherman
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExampleSelectors(t *testing.T) {
+	examples := []postman.Response{{Name: "ok", Status: 200}, {Name: "missing", Status: 404}, {Name: "also missing", Status: 404}}
+
+	tests := []struct {
+		name     string
+		pick     exampleSelector
+		examples []postman.Response
+		index    int
+		err      string
+	}{
+		{"first", firstExample, examples, 0, ""},
+		{"named", exampleNamed("missing"), examples, 1, ""},
+		{"first with status", exampleWithStatus(404), examples, 1, ""},
+		{"unknown name", exampleNamed("gone"), examples, 0, "has no example named gone"},
+		{"unknown status", exampleWithStatus(500), examples, 0, "has no example with status 500"},
+		{"no examples", firstExample, nil, 0, errNoExamples.Error()},
+		{"no examples to name", exampleNamed("ok"), nil, 0, errNoExamples.Error()},
+		{"no examples with status", exampleWithStatus(200), nil, 0, errNoExamples.Error()},
+	}
+
+	for _, test := range tests {
+		index, err := test.pick(test.examples)
+		if test.err != "" {
+			if assert.Error(t, err, test.name) {
+				assert.Equal(t, test.err, err.Error(), test.name)
+			}
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.index, index, test.name)
+	}
+}
+
+func TestRequestWithExample(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/1" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"id":1}`)
+	}))
+	defer srv.Close()
+
+	user := postman.CollectionItem{
+		Name:    "user",
+		Request: postman.Request{Method: http.MethodGet, URL: postman.URL{Raw: "http://localhost/users/1"}},
+		Response: []postman.Response{
+			{Name: "found", Status: 200, Raw: `{"id":1}`},
+			{
+				Name:            "missing",
+				Status:          404,
+				Raw:             `{"error":"not found"}`,
+				OriginalRequest: &postman.Request{Method: http.MethodGet, URL: postman.URL{Raw: "http://localhost/users/2"}},
+			},
+		},
+	}
+
+	dir, file := writeCollection(t, user, postman.CollectionItem{Name: "bare", Request: user.Request})
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithServer(srv, dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Empty(t, tester.TestRequestWithName("user", t, DefaultHandler))
+	assert.Empty(t, tester.TestRequestWithExample("user", "found", t, DefaultHandler))
+
+	// the missing example's originalRequest asks for a user that doesn't exist
+	assert.Empty(t, tester.TestRequestWithExample("user", "missing", t, DefaultHandler))
+	assert.Empty(t, tester.TestRequestWithStatus("user", 404, t, DefaultHandler))
+
+	errs := tester.TestRequestWithExample("user", "gone", t, DefaultHandler)
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "item with name user: has no example named gone")
+	}
+
+	// items without examples fail rather than panic, unless they're skipped
+	errs = tester.TestRequestWithName("bare", t, DefaultHandler)
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "has no example responses to compare against")
+	}
+
+	tester.SkipMissingExamples = true
+
+	var skipped *testing.T
+	t.Run("skipped", func(st *testing.T) {
+		skipped = st
+		tester.TestRequestWithName("bare", st, DefaultHandler)
+	})
+
+	assert.True(t, skipped.Skipped())
+}
@@ -0,0 +1,103 @@
+package gopherman
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// ExtractBody declares an extraction of the value at a JSON path in the response body
+func ExtractBody(variable, path string) postman.Extraction {
+	return postman.Extraction{Variable: variable, From: postman.FromBody, Path: path}
+}
+
+// ExtractHeader declares an extraction of a response header
+func ExtractHeader(variable, name string) postman.Extraction {
+	return postman.Extraction{Variable: variable, From: postman.FromHeader, Path: name}
+}
+
+// ExtractCookie declares an extraction of a cookie set by the response
+func ExtractCookie(variable, name string) postman.Extraction {
+	return postman.Extraction{Variable: variable, From: postman.FromCookie, Path: name}
+}
+
+// Extract declares extractions to run after the named item's request, in addition to any
+// annotated in the collection. Extracted values are set as runtime variables for later requests
+func (t *Tester) Extract(name string, extractions ...postman.Extraction) {
+	if t.extractions == nil {
+		t.extractions = map[string][]postman.Extraction{}
+	}
+
+	t.extractions[name] = append(t.extractions[name], extractions...)
+}
+
+// extractionsFor returns an item's annotated extractions followed by those declared in Go
+func (t *Tester) extractionsFor(itm *postman.CollectionItem) []postman.Extraction {
+	return append(itm.Extractions(), t.extractions[itm.Name]...)
+}
+
+// extract runs extractions against a response, setting the results in scope
+func extract(scope *postman.Scope, extractions []postman.Extraction, resp *postman.Response, header http.Header) error {
+	for _, ex := range extractions {
+		val, err := extractValue(ex, resp, header)
+		if err != nil {
+			return errors.Wrapf(err, "failed to extract %s", ex.Variable)
+		}
+
+		scope.Set(ex.Variable, val)
+	}
+
+	return nil
+}
+
+func extractValue(ex postman.Extraction, resp *postman.Response, header http.Header) (string, error) {
+	switch ex.From {
+	case postman.FromHeader:
+		if _, ok := header[http.CanonicalHeaderKey(ex.Path)]; !ok {
+			return "", fmt.Errorf("response has no header %s", ex.Path)
+		}
+
+		return header.Get(ex.Path), nil
+
+	case postman.FromCookie:
+		for _, c := range (&http.Response{Header: header}).Cookies() {
+			if c.Name == ex.Path {
+				return c.Value, nil
+			}
+		}
+
+		return "", fmt.Errorf("response sets no cookie %s", ex.Path)
+
+	case postman.FromBody, "":
+		path, err := parsePath(ex.Path)
+		if err != nil {
+			return "", err
+		}
+
+		var body interface{}
+		if err := json.Unmarshal([]byte(resp.Raw), &body); err != nil {
+			return "", errors.Wrap(err, "response body is not JSON")
+		}
+
+		val, ok := lookupPath(body, path)
+		if !ok {
+			return "", fmt.Errorf("response body has no value at %s", ex.Path)
+		}
+
+		if str, ok := val.(string); ok {
+			return str, nil
+		}
+
+		valJSON, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+
+		return string(valJSON), nil
+	}
+
+	return "", fmt.Errorf("unknown extraction source %s", ex.From)
+}
@@ -21,9 +21,9 @@ func (w *FakeWriter) WriteHeader(statusCode int) {
 	w.StatusCode = statusCode
 }
 
-// Write writes the response body
+// Write appends to the response body. body is copied, since callers may reuse it
 func (w *FakeWriter) Write(body []byte) (int, error) {
-	w.Body = body
+	w.Body = append(w.Body, body...)
 
 	if w.StatusCode == 0 {
 		w.StatusCode = http.StatusOK
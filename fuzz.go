@@ -0,0 +1,409 @@
+package gopherman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// Kinds of fuzz finding
+const (
+	FindingServerError = "5xx"
+	FindingPanic       = "panic"
+	FindingTimeout     = "timeout"
+	// FindingConnection is a dropped connection, which is how net/http servers respond when a handler panics
+	FindingConnection = "connection"
+)
+
+// defaultFuzzTimeout is how long a mutated request may take when neither FuzzOptions.Timeout nor Tester.Timeout is set
+const defaultFuzzTimeout = 10 * time.Second
+
+// FuzzOptions configures a fuzz run. See Tester.Fuzz
+type FuzzOptions struct {
+	// Items are the names of the items to fuzz, every item if empty
+	Items []string
+
+	// Mutations is the most mutations to send per item, chosen at random using Seed. Zero sends every mutation
+	Mutations int
+	Seed      int64
+
+	// Timeout is how long a mutated request may take before it's a finding, Tester.Timeout if zero
+	Timeout time.Duration
+
+	// Output is a collection file to write the findings' reproducing requests to, if set
+	Output string
+}
+
+// Finding is a mutated request that made the server fail, crash or hang
+type Finding struct {
+	Collection string
+	Item       string
+	// Mutation describes the change made to the item's request, e.g. body $.name: huge string
+	Mutation string
+	Kind     string
+	Status   int
+	Error    string
+
+	// Request reproduces the finding. Headers, query parameters and body fields that aren't
+	// needed to reproduce it are removed
+	Request postman.Request
+}
+
+func (f *Finding) String() string {
+	detail := f.Error
+	if f.Kind == FindingServerError {
+		detail = fmt.Sprintf("status %d", f.Status)
+	}
+
+	return fmt.Sprintf("%s/%s: %s caused %s (%s)", f.Collection, f.Item, f.Mutation, f.Kind, detail)
+}
+
+// FuzzReport is the outcome of a fuzz run
+type FuzzReport struct {
+	Items    int
+	Requests int
+	Findings []Finding
+}
+
+// Fuzz sends mutated copies of each item's request, changing JSON body values, query parameters
+// and headers one at a time, and reports every mutation that produces a 5xx status, a panic
+// in an in-process handler, a timeout or a dropped connection. Items are first sent unmutated,
+// in order, so that values they extract are available to later items; an item whose unmutated
+// request already fails is reported once and not mutated
+func (t *Tester) Fuzz(ctx context.Context, opts FuzzOptions) (*FuzzReport, error) {
+	if err := t.WaitReady(ctx); err != nil {
+		return nil, err
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = t.Timeout
+	}
+
+	if timeout == 0 {
+		timeout = defaultFuzzTimeout
+	}
+
+	f := &fuzzer{
+		tester:  t,
+		opts:    opts,
+		timeout: timeout,
+		rand:    rand.New(rand.NewSource(opts.Seed)),
+		report:  &FuzzReport{},
+	}
+
+	for i := range t.Collections {
+		collection := &t.Collections[i]
+
+		var err error
+		collection.Walk(func(folders []string, itm *postman.CollectionItem) {
+			if err != nil || ctx.Err() != nil || !f.selected(itm) {
+				return
+			}
+
+			path := strings.Join(append(append([]string{}, folders...), itm.Name), "/")
+			err = f.fuzzItem(ctx, collection, path, itm)
+		})
+
+		if err != nil {
+			return f.report, err
+		}
+	}
+
+	if opts.Output != "" && len(f.report.Findings) > 0 {
+		if err := f.report.WriteCollection(opts.Output); err != nil {
+			return f.report, err
+		}
+	}
+
+	return f.report, ctx.Err()
+}
+
+// RunFuzz runs Fuzz and fails tst with an error for each finding
+func (t *Tester) RunFuzz(tst *testing.T, opts FuzzOptions) *FuzzReport {
+	tst.Helper()
+
+	report, err := t.Fuzz(context.Background(), opts)
+	if err != nil {
+		tst.Fatal(err)
+	}
+
+	tst.Logf("fuzzed %d item(s) with %d request(s)", report.Items, report.Requests)
+
+	for i := range report.Findings {
+		tst.Error(t.Secrets.Mask(report.Findings[i].String()))
+	}
+
+	return report
+}
+
+// Collection returns a collection with an item reproducing each finding
+func (r *FuzzReport) Collection(name string) *postman.Collection {
+	items := []postman.CollectionItem{}
+
+	for _, f := range r.Findings {
+		items = append(items, postman.CollectionItem{
+			Name:     fmt.Sprintf("%s [%s] %s", f.Item, f.Kind, f.Mutation),
+			Request:  f.Request,
+			Response: []postman.Response{},
+		})
+	}
+
+	return postman.NewCollection(name, items, nil)
+}
+
+// WriteCollection writes the findings' reproducing requests to a collection file
+func (r *FuzzReport) WriteCollection(path string) error {
+	collectionJSON, err := json.MarshalIndent(r.Collection("fuzz findings"), "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to Marshal collection")
+	}
+
+	return ioutil.WriteFile(path, collectionJSON, 0644)
+}
+
+// fuzzer holds the state of a fuzz run
+type fuzzer struct {
+	tester  *Tester
+	opts    FuzzOptions
+	timeout time.Duration
+	rand    *rand.Rand
+	report  *FuzzReport
+}
+
+func (f *fuzzer) selected(itm *postman.CollectionItem) bool {
+	return selectedItem(f.opts.Items, itm)
+}
+
+// selectedItem returns true if itm is named in names, or names is empty
+func selectedItem(names []string, itm *postman.CollectionItem) bool {
+	if len(names) == 0 {
+		return true
+	}
+
+	for _, name := range names {
+		if name == itm.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// outcome is how the server responded to a request, with kind empty if it wasn't a finding
+type outcome struct {
+	kind   string
+	status int
+	err    string
+}
+
+func (f *fuzzer) fuzzItem(ctx context.Context, collection *postman.Collection, path string, itm *postman.CollectionItem) error {
+	t := f.tester
+	f.report.Items++
+
+	scope, err := t.scopeFor(collection)
+	if err != nil {
+		return err
+	}
+
+	// the unmutated request runs like a normal one, so that later items can use its values
+	resp, header, out := f.send(ctx, &itm.Request, scope)
+	if out.kind != "" {
+		f.addFinding(collection, path, "none (unmutated request)", out, itm.Request)
+		return nil
+	}
+
+	if resp != nil {
+		// values that can't be extracted only matter to later items, which will report their own failures
+		extract(scope, t.extractionsFor(itm), resp, header)
+	}
+
+	muts := mutants(&itm.Request)
+	if f.opts.Mutations > 0 && len(muts) > f.opts.Mutations {
+		f.rand.Shuffle(len(muts), func(i, j int) { muts[i], muts[j] = muts[j], muts[i] })
+		muts = muts[:f.opts.Mutations]
+	}
+
+	// one finding per kind of failure per mutated part is enough to go on
+	found := map[string]bool{}
+
+	for _, m := range muts {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		_, _, out := f.send(ctx, &m.req, scope)
+		if out.kind == "" {
+			continue
+		}
+
+		key := m.part + " " + m.name + " " + out.kind
+		if found[key] {
+			continue
+		}
+
+		found[key] = true
+
+		req := f.minimize(ctx, m, out, scope)
+		f.addFinding(collection, path, m.desc, out, req)
+	}
+
+	return nil
+}
+
+func (f *fuzzer) addFinding(collection *postman.Collection, path, mutation string, out outcome, req postman.Request) {
+	f.report.Findings = append(f.report.Findings, Finding{
+		Collection: collection.Info.Name,
+		Item:       path,
+		Mutation:   mutation,
+		Kind:       out.kind,
+		Status:     out.status,
+		Error:      out.err,
+		Request:    req,
+	})
+}
+
+// send sends a request and classifies the outcome
+func (f *fuzzer) send(ctx context.Context, req *postman.Request, scope *postman.Scope) (*postman.Response, map[string][]string, outcome) {
+	f.report.Requests++
+
+	httpReq, client, err := f.tester.buildRequest(req, scope)
+	if err != nil {
+		// the mutation made a request that can't be sent, which isn't the server's fault
+		return nil, nil, outcome{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	resp, header, err := makeRequest(client, httpReq.WithContext(ctx))
+	if err != nil {
+		return nil, nil, classifyError(ctx, err)
+	}
+
+	if resp.Status >= 500 {
+		return resp, header, outcome{kind: FindingServerError, status: resp.Status}
+	}
+
+	return resp, header, outcome{}
+}
+
+func classifyError(ctx context.Context, err error) outcome {
+	cause := rootCause(err)
+
+	if p, ok := cause.(*PanicError); ok {
+		return outcome{kind: FindingPanic, err: p.Error()}
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return outcome{kind: FindingTimeout, err: err.Error()}
+	}
+
+	if cause == io.EOF || cause == io.ErrUnexpectedEOF || cause == syscall.ECONNRESET {
+		return outcome{kind: FindingConnection, err: err.Error()}
+	}
+
+	// e.g. a header value the client refuses to send
+	return outcome{}
+}
+
+// rootCause unwraps err through pkg/errors causes and the url, net and os errors a failed request is wrapped in
+func rootCause(err error) error {
+	for {
+		err = errors.Cause(err)
+
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		default:
+			return err
+		}
+	}
+}
+
+// minimize removes each header, query parameter and top-level body field that the mutation
+// didn't change, keeping each removal that still reproduces the finding
+func (f *fuzzer) minimize(ctx context.Context, m mutant, want outcome, scope *postman.Scope) postman.Request {
+	req := copyRequest(&m.req)
+
+	reproduces := func(candidate postman.Request) bool {
+		_, _, out := f.send(ctx, &candidate, scope)
+		return out.kind == want.kind && out.status == want.status
+	}
+
+	for i := len(req.Header) - 1; i >= 0; i-- {
+		if m.part == mutatedHeader && req.Header[i].Key == m.name {
+			continue
+		}
+
+		candidate := copyRequest(&req)
+		candidate.Header = append(candidate.Header[:i], candidate.Header[i+1:]...)
+
+		if reproduces(candidate) {
+			req = candidate
+		}
+	}
+
+	base, params := splitQuery(req.URL.Raw)
+	for i := len(params) - 1; i >= 0; i-- {
+		if m.part == mutatedQuery && paramName(params[i]) == m.name {
+			continue
+		}
+
+		candidate := copyRequest(&req)
+		candidate.URL.Raw = joinQuery(base, withoutString(params, i))
+
+		if reproduces(candidate) {
+			req = candidate
+			params = withoutString(params, i)
+		}
+	}
+
+	if m.part == mutatedBody && m.name == "" {
+		// the whole body was replaced, so there are no fields to remove
+		return req
+	}
+
+	root, err := parseOrderedJSON([]byte(req.Body.Raw))
+	if err != nil || !root.isObject {
+		return req
+	}
+
+	for i := len(root.keys) - 1; i >= 0; i-- {
+		key := root.keys[i]
+		if m.part == mutatedBody && key == m.name {
+			continue
+		}
+
+		body, ok := replaceNode(req.Body.Raw, []pathSegment{keySeg(key)}, nil)
+		if !ok {
+			continue
+		}
+
+		candidate := copyRequest(&req)
+		candidate.Body.Raw = body
+
+		if reproduces(candidate) {
+			req = candidate
+		}
+	}
+
+	return req
+}
@@ -0,0 +1,184 @@
+package gopherman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCollection writes a collection of items to a temporary file, returning its directory and name
+func writeCollection(t *testing.T, items ...postman.CollectionItem) (string, string) {
+	data, err := json.Marshal(postman.NewCollection("test", items, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writeTempFile(t, "collection.json", data), "collection.json"
+}
+
+// writeTempFile writes data to a file in a new temporary directory, returning the directory
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// fuzzHandler panics when count is zero, fails when name is huge and hangs when limit isn't a number
+func fuzzHandler(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Name  string
+		Count *int
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case body.Count != nil && *body.Count == 0:
+		panic("division by zero")
+	case len(body.Name) > 1000:
+		w.WriteHeader(http.StatusInternalServerError)
+	case r.URL.Query().Get("limit") == "NaN":
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	default:
+		fmt.Fprint(w, `{"ok":true}`)
+	}
+}
+
+func fuzzItem() postman.CollectionItem {
+	return postman.CollectionItem{
+		Name: "create",
+		Request: postman.Request{
+			Method: http.MethodPost,
+			Header: []postman.Header{{Key: "X-Client", Value: "test"}},
+			Body:   postman.Body{Mode: "raw", Raw: `{"name":"a","count":1}`},
+			URL:    postman.URL{Raw: "http://localhost/things?limit=10"},
+		},
+	}
+}
+
+// findingsByMutation indexes findings by their mutation and kind
+func findingsByMutation(report *FuzzReport) map[string]Finding {
+	findings := map[string]Finding{}
+	for _, f := range report.Findings {
+		findings[f.Mutation+" "+f.Kind] = f
+	}
+
+	return findings
+}
+
+func TestFuzzServer(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(fuzzHandler))
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.Start()
+
+	defer srv.Close()
+
+	dir, file := writeCollection(t, fuzzItem())
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithServer(srv, dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := tester.Fuzz(context.Background(), FuzzOptions{Timeout: 100 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Items)
+
+	findings := findingsByMutation(report)
+	assert.Len(t, findings, 3)
+
+	huge, ok := findings["body $.name: huge string 5xx"]
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusInternalServerError, huge.Status)
+		assert.Empty(t, huge.Request.Header)
+		assert.Equal(t, "http://localhost/things", huge.Request.URL.Raw)
+		assert.Equal(t, fmt.Sprintf(`{"name":"%s"}`, hugeString), huge.Request.Body.Raw)
+	}
+
+	dropped, ok := findings["body $.count: zero connection"]
+	if assert.True(t, ok) {
+		assert.Equal(t, `{"count":0}`, dropped.Request.Body.Raw)
+	}
+
+	_, ok = findings["query limit: not a number timeout"]
+	assert.True(t, ok)
+}
+
+func TestFuzzHandler(t *testing.T) {
+	dir, file := writeCollection(t, fuzzItem())
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithHandler(http.HandlerFunc(fuzzHandler), dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := tester.Fuzz(context.Background(), FuzzOptions{Items: []string{"create"}, Timeout: 100 * time.Millisecond})
+	assert.NoError(t, err)
+
+	panicked, ok := findingsByMutation(report)["body $.count: zero panic"]
+	if assert.True(t, ok) {
+		assert.Contains(t, panicked.Error, "division by zero")
+	}
+
+	report, err = tester.Fuzz(context.Background(), FuzzOptions{Items: []string{"other"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Items)
+}
+
+func TestClassifyError(t *testing.T) {
+	reset := &url.Error{Op: "Post", URL: "http://localhost", Err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}
+
+	cases := []struct {
+		name string
+		err  error
+		kind string
+	}{
+		{"EOF", &url.Error{Op: "Post", URL: "http://localhost", Err: io.EOF}, FindingConnection},
+		{"unexpected EOF", errors.Wrap(io.ErrUnexpectedEOF, "failed to read body"), FindingConnection},
+		{"connection reset", errors.Wrap(reset, "failed to send"), FindingConnection},
+		{"panic", &url.Error{Op: "Post", URL: "http://localhost", Err: &PanicError{Value: "boom"}}, FindingPanic},
+		{"EOF in a message", errors.New(`invalid header value "EOF"`), ""},
+		{"refused", &url.Error{Op: "Post", URL: "http://localhost", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, ""},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.kind, classifyError(context.Background(), tc.err).kind, tc.name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	<-ctx.Done()
+	assert.Equal(t, FindingTimeout, classifyError(ctx, errors.New("context deadline exceeded")).kind)
+}
@@ -0,0 +1,369 @@
+package gopherman
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// UpdateEnvVar enables update mode when set to a true value
+const UpdateEnvVar = "GOPHERMAN_UPDATE"
+
+// UpdateFlag is the name of the flag added by RegisterUpdateFlag
+const UpdateFlag = "gopherman.update"
+
+// RegisterUpdateFlag adds the -gopherman.update flag, which enables update mode like $GOPHERMAN_UPDATE.
+// It isn't added automatically, so that importing gopherman doesn't give every binary a flag; call it
+// from a test package's init or TestMain, before flags are parsed. Calling it more than once is harmless
+func RegisterUpdateFlag() {
+	if flag.Lookup(UpdateFlag) == nil {
+		flag.Bool(UpdateFlag, false, "rewrite the expected responses in collection files with the actual responses")
+	}
+}
+
+// exampleUpdate is a pending rewrite of one item's example
+type exampleUpdate struct {
+	collection int
+	items      []int
+	example    int
+	path       string
+	old        *postman.Response
+	new        postman.Response
+}
+
+// updating returns true if the tester is in update mode, which records actual responses
+// as the items' examples instead of comparing against them
+func (t *Tester) updating() bool {
+	if t.Update {
+		return true
+	}
+
+	if f := flag.Lookup(UpdateFlag); f != nil {
+		if update, _ := strconv.ParseBool(f.Value.String()); update {
+			return true
+		}
+	}
+
+	update, _ := strconv.ParseBool(os.Getenv(UpdateEnvVar))
+
+	return update
+}
+
+// queueUpdate replaces an item's example with actual, in memory now and in its file on WriteUpdates
+func (t *Tester) queueUpdate(collection *postman.Collection, path string, itm *postman.CollectionItem, example int, actual *postman.Response) {
+	update := exampleUpdate{
+		collection: -1,
+		example:    example,
+		path:       path,
+		new:        *actual,
+	}
+
+	update.new.Header = stableHeaders(actual.Header, t.IgnoreHeaders)
+
+	for i := range t.Collections {
+		if &t.Collections[i] == collection {
+			update.collection = i
+		}
+	}
+
+	update.items = itemIndexPath(collection.Item, itm)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if example < len(itm.Response) {
+		old := itm.Response[example]
+		old.Header = stableHeaders(old.Header, t.IgnoreHeaders)
+		update.old = &old
+
+		itm.Response[example].Raw = actual.Raw
+		itm.Response[example].Status = actual.Status
+		itm.Response[example].Header = update.new.Header
+	} else {
+		update.example = len(itm.Response)
+		itm.Response = append(itm.Response, postman.Response{Mode: "raw", Raw: actual.Raw, Status: actual.Status, Header: update.new.Header})
+	}
+
+	t.updates = append(t.updates, update)
+}
+
+// stableHeaders returns headers without VolatileHeaders and ignore, sorted by key,
+// so that examples only record headers that are expected to be the same every time
+func stableHeaders(headers []postman.Header, ignore []string) []postman.Header {
+	header := (&postman.Response{Header: headers}).HTTPHeader()
+
+	for _, name := range append(append([]string{}, VolatileHeaders...), ignore...) {
+		header.Del(name)
+	}
+
+	return postman.HeadersFromHTTP(header)
+}
+
+// itemIndexPath returns the indices leading from items to itm through any folders
+func itemIndexPath(items []postman.CollectionItem, itm *postman.CollectionItem) []int {
+	for i := range items {
+		if &items[i] == itm {
+			return []int{i}
+		}
+
+		if items[i].IsFolder() {
+			if path := itemIndexPath(items[i].Item, itm); path != nil {
+				return append([]int{i}, path...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteUpdates writes the examples recorded in update mode back to their collection files.
+// Only the updated examples change; everything else in the files, including key order, is kept
+func (t *Tester) WriteUpdates() error {
+	t.mu.Lock()
+	updates := t.updates
+	t.updates = nil
+	t.mu.Unlock()
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	byFile := map[int][]exampleUpdate{}
+	order := []int{}
+
+	for _, u := range updates {
+		if _, ok := byFile[u.collection]; !ok {
+			order = append(order, u.collection)
+		}
+
+		byFile[u.collection] = append(byFile[u.collection], u)
+	}
+
+	summary := &bytes.Buffer{}
+	changed := 0
+
+	for _, i := range order {
+		if i < 0 || i >= len(t.files) || t.files[i] == "" {
+			return errors.New("can't update a collection that wasn't loaded from a file")
+		}
+
+		n, err := rewriteExamples(t.files[i], byFile[i], summary)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update %s", t.files[i])
+		}
+
+		changed += n
+	}
+
+	fmt.Printf("gopherman: updated %d example(s)\n%s", changed, summary)
+
+	return nil
+}
+
+// rewriteExamples applies updates to the collection file at path, describing each change in summary
+func rewriteExamples(path string, updates []exampleUpdate, summary *bytes.Buffer) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	root, err := parseOrderedJSON(data)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse collection")
+	}
+
+	changed := 0
+
+	for _, u := range updates {
+		desc := describeUpdate(u)
+		if desc == "" {
+			continue
+		}
+
+		node := root
+		for _, index := range u.items {
+			items := node.get("item")
+			if items == nil || !items.isArray || index >= len(items.items) {
+				return 0, fmt.Errorf("item %s not found in file", u.path)
+			}
+
+			node = items.items[index]
+		}
+
+		postmanKeys := postmanStyle(node)
+
+		examples := node.get("response")
+		if examples == nil || !examples.isArray {
+			examples = &jsonNode{isArray: true, items: []*jsonNode{}}
+			node.set(styledKey("response", postmanKeys), examples)
+		}
+
+		if u.example < len(examples.items) {
+			setExample(examples.items[u.example], u.new, postmanKeys)
+		} else {
+			example := &jsonNode{isObject: true}
+			if !postmanKeys {
+				example.set("Mode", newValueNode("raw"))
+			}
+
+			setExample(example, u.new, postmanKeys)
+			examples.add(example)
+		}
+
+		fmt.Fprintf(summary, "  %s: %s: %s\n", path, u.path, desc)
+		changed++
+	}
+
+	if changed == 0 {
+		return 0, nil
+	}
+
+	buf := &bytes.Buffer{}
+	root.write(buf, detectIndent(data), 0)
+
+	if bytes.HasSuffix(data, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+
+	return changed, ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// postmanStyle returns true if an item's keys are spelled the Postman way, e.g. "request",
+// rather than the way gopherman writes them, e.g. "Request"
+func postmanStyle(itm *jsonNode) bool {
+	for _, k := range itm.keys {
+		if strings.EqualFold(k, "request") {
+			return k == "request"
+		}
+	}
+
+	return true
+}
+
+// styledKey returns key, a lower case Postman key, as gopherman spells it unless postmanKeys is set
+func styledKey(key string, postmanKeys bool) string {
+	if postmanKeys {
+		return key
+	}
+
+	return strings.Title(key)
+}
+
+// setExample sets an example's body, status and headers. It uses Postman's body, code, status
+// and header keys if postmanKeys is set or the example already has them, and gopherman's otherwise.
+// New keys are added in the order Postman or gopherman writes them
+func setExample(example *jsonNode, resp postman.Response, postmanKeys bool) {
+	if postmanKeys {
+		setExampleStatus(example, resp, postmanKeys)
+		setExampleHeader(example, resp, postmanKeys)
+		setExampleBody(example, resp, postmanKeys)
+	} else {
+		setExampleBody(example, resp, postmanKeys)
+		setExampleStatus(example, resp, postmanKeys)
+		setExampleHeader(example, resp, postmanKeys)
+	}
+}
+
+func setExampleBody(example *jsonNode, resp postman.Response, postmanKeys bool) {
+	if postmanKeys || example.has("body") {
+		example.set("body", newValueNode(resp.Raw))
+	} else {
+		example.set("Raw", newValueNode(resp.Raw))
+	}
+}
+
+func setExampleStatus(example *jsonNode, resp postman.Response, postmanKeys bool) {
+	if postmanKeys || example.has("code") {
+		// Postman's status is the reason text, e.g. "Not Found". New examples get one,
+		// existing ones only if they had one
+		if example.has("status") || !example.has("code") {
+			example.set("status", newValueNode(http.StatusText(resp.Status)))
+		}
+
+		example.set("code", newValueNode(resp.Status))
+		return
+	}
+
+	example.set("Status", newValueNode(resp.Status))
+
+	if example.has("StatusText") {
+		example.set("StatusText", newValueNode(http.StatusText(resp.Status)))
+	}
+}
+
+func setExampleHeader(example *jsonNode, resp postman.Response, postmanKeys bool) {
+	if postmanKeys || example.has("header") {
+		headers := []map[string]string{}
+		for _, h := range resp.Header {
+			headers = append(headers, map[string]string{"key": h.Key, "value": h.Value})
+		}
+
+		example.set("header", newValueNode(headers))
+	} else if example.has("Header") || len(resp.Header) > 0 {
+		example.set("Header", newValueNode(resp.Header))
+	}
+}
+
+// describeUpdate summarises what an update changes, or returns "" if it changes nothing
+func describeUpdate(u exampleUpdate) string {
+	if u.old == nil {
+		return fmt.Sprintf("added example with status %d", u.new.Status)
+	}
+
+	desc := ""
+
+	if u.old.Status != u.new.Status {
+		desc = fmt.Sprintf("status %d -> %d", u.old.Status, u.new.Status)
+	}
+
+	if u.old.Raw != u.new.Raw {
+		if desc != "" {
+			desc += ", "
+		}
+
+		desc += "body changed"
+	}
+
+	if changed := changedHeaders(u.old.Header, u.new.Header); len(changed) > 0 {
+		if desc != "" {
+			desc += ", "
+		}
+
+		desc += "headers changed: " + strings.Join(changed, ", ")
+	}
+
+	return desc
+}
+
+// changedHeaders returns the names of headers that were added, removed or given different values, sorted
+func changedHeaders(old, new []postman.Header) []string {
+	oldHeader := (&postman.Response{Header: old}).HTTPHeader()
+	newHeader := (&postman.Response{Header: new}).HTTPHeader()
+
+	changed := []string{}
+	for _, h := range postman.HeadersFromHTTP(oldHeader) {
+		if !reflect.DeepEqual(oldHeader[h.Key], newHeader[h.Key]) && !containsString(changed, h.Key) {
+			changed = append(changed, h.Key)
+		}
+	}
+
+	for _, h := range postman.HeadersFromHTTP(newHeader) {
+		if _, ok := oldHeader[h.Key]; !ok && !containsString(changed, h.Key) {
+			changed = append(changed, h.Key)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
@@ -0,0 +1,144 @@
+package gopherman
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteExamplesPreservesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	original := `{"info":{"name":"café"},"item":[{"name":"get","request":{"method":"GET","url":{"raw":"/a?x=1&y=<2>"}},"response":[{"name":"ok","status":"OK","code":200,"header":[{"key":"Content-Type","value":"text/plain"}],"body":"{\"v\":1}"}]},{"name":"new","request":{"method":"GET","url":{"raw":"/b"}}}]}` + "\n"
+
+	path := filepath.Join(dir, "collection.json")
+	if err := ioutil.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	collection := postman.Collection{}
+	if err := json.Unmarshal([]byte(original), &collection); err != nil {
+		t.Fatal(err)
+	}
+
+	tester := &Tester{
+		Collections:   []postman.Collection{collection},
+		IgnoreHeaders: []string{"X-Trace"},
+		files:         []string{path},
+	}
+
+	header := []postman.Header{
+		{Key: "Date", Value: "Mon, 01 Jan 2018 00:00:00 GMT"},
+		{Key: "X-Trace", Value: "abc"},
+		{Key: "Content-Type", Value: "application/json"},
+	}
+
+	items := tester.Collections[0].Item
+	tester.queueUpdate(&tester.Collections[0], "get", &items[0], 0, &postman.Response{Status: 201, Raw: `{"v":2}`, Header: header})
+	tester.queueUpdate(&tester.Collections[0], "new", &items[1], 0, &postman.Response{Status: 200, Raw: "ok", Header: header[:2]})
+
+	stable := []postman.Header{{Key: "Content-Type", Value: "application/json", Type: "text"}}
+	assert.Equal(t, stable, items[0].Response[0].Header)
+	assert.Equal(t, []postman.Header{}, items[1].Response[0].Header)
+
+	summary := &bytes.Buffer{}
+
+	changed, err := rewriteExamples(path, tester.updates, summary)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, changed)
+
+	written, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	expected := `{"info":{"name":"café"},"item":[{"name":"get","request":{"method":"GET","url":{"raw":"/a?x=1&y=<2>"}},"response":[{"name":"ok","status":"Created","code":201,"header":[` +
+		"\n\t\t\t\t\t\t{\n\t\t\t\t\t\t\t\"key\": \"Content-Type\",\n\t\t\t\t\t\t\t\"value\": \"application/json\"\n\t\t\t\t\t\t}\n\t\t\t\t\t]" +
+		`,"body":"{\"v\":2}"}]},{` +
+		"\n\t\t\t\"name\": \"new\",\n\t\t\t\"request\": {\"method\":\"GET\",\"url\":{\"raw\":\"/b\"}},\n\t\t\t\"response\": [\n\t\t\t\t{\n\t\t\t\t\t\"status\": \"OK\",\n\t\t\t\t\t\"code\": 200,\n\t\t\t\t\t\"header\": [],\n\t\t\t\t\t\"body\": \"ok\"\n\t\t\t\t}\n\t\t\t]\n\t\t}]}\n"
+	assert.Equal(t, expected, string(written))
+
+	assert.Contains(t, summary.String(), "get: status 200 -> 201, body changed, headers changed: Content-Type\n")
+	assert.Contains(t, summary.String(), "new: added example with status 200\n")
+}
+
+func TestRewriteExamplesGophermanKeys(t *testing.T) {
+	collection := postman.NewCollection("test", []postman.CollectionItem{
+		{Name: "get", Request: postman.Request{Method: "GET", URL: postman.URL{Raw: "/a"}}, Response: []postman.Response{{Mode: "raw", Raw: "a", Status: 200}}},
+		{Name: "new", Request: postman.Request{Method: "GET", URL: postman.URL{Raw: "/b"}}},
+	}, nil)
+
+	dir, file := writeCollection(t, collection.Item...)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, file)
+
+	tester := &Tester{Collections: []postman.Collection{*collection}, files: []string{path}}
+
+	items := tester.Collections[0].Item
+	tester.queueUpdate(&tester.Collections[0], "get", &items[0], 0, &postman.Response{Status: 404, Raw: "b"})
+	tester.queueUpdate(&tester.Collections[0], "new", &items[1], 0, &postman.Response{Status: 200, Raw: "ok"})
+
+	changed, err := rewriteExamples(path, tester.updates, &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, changed)
+
+	written, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	root, err := parseOrderedJSON(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, keys := range [][]string{{"Mode", "Raw", "Status"}, {"Mode", "Raw", "Status"}} {
+		example := root.get("Item").items[i].get("Response").items[0]
+		assert.Equal(t, keys, example.keys[:3])
+		assert.False(t, example.has("code"))
+	}
+
+	updated := postman.Collection{}
+	assert.NoError(t, json.Unmarshal(written, &updated))
+	assert.Equal(t, 404, updated.Item[0].Response[0].Status)
+	assert.Equal(t, "b", updated.Item[0].Response[0].Raw)
+	assert.Equal(t, "ok", updated.Item[1].Response[0].Raw)
+}
+
+func TestDescribeUpdate(t *testing.T) {
+	header := func(kv ...string) []postman.Header {
+		headers := []postman.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			headers = append(headers, postman.Header{Key: kv[i], Value: kv[i+1]})
+		}
+
+		return headers
+	}
+
+	cases := []struct {
+		name string
+		old  *postman.Response
+		new  postman.Response
+		desc string
+	}{
+		{"added", nil, postman.Response{Status: 204}, "added example with status 204"},
+		{"unchanged", &postman.Response{Status: 200, Raw: "a", Header: header("A", "1")}, postman.Response{Status: 200, Raw: "a", Header: header("a", "1")}, ""},
+		{"status", &postman.Response{Status: 200}, postman.Response{Status: 404}, "status 200 -> 404"},
+		{"body", &postman.Response{Raw: "a"}, postman.Response{Raw: "b"}, "body changed"},
+		{"headers", &postman.Response{Header: header("A", "1", "B", "1", "C", "1")}, postman.Response{Header: header("A", "2", "C", "1", "D", "1")}, "headers changed: A, B, D"},
+		{"header values", &postman.Response{Header: header("Vary", "a", "Vary", "b")}, postman.Response{Header: header("Vary", "a")}, "headers changed: Vary"},
+		{"everything", &postman.Response{Status: 200, Raw: "a"}, postman.Response{Status: 201, Raw: "b", Header: header("A", "1")}, "status 200 -> 201, body changed, headers changed: A"},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.desc, describeUpdate(exampleUpdate{old: tc.old, new: tc.new}), tc.name)
+	}
+}
@@ -0,0 +1,157 @@
+package gopherman
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/cohix/gopherman/postman"
+)
+
+// VolatileHeaders change from one response to the next, so they're left out of CompareHeaders
+var VolatileHeaders = []string{
+	"Age",
+	"Connection",
+	"Content-Length",
+	"Date",
+	"Etag",
+	"Expires",
+	"Keep-Alive",
+	"Last-Modified",
+	"Server-Timing",
+	"Set-Cookie",
+	"Transfer-Encoding",
+	"X-Request-Id",
+}
+
+// HeaderPresent asserts that a header is present, with any value
+func HeaderPresent(name string) postman.HeaderAssertion {
+	return postman.HeaderAssertion{Name: name}
+}
+
+// HeaderValue asserts that a header has exactly value
+func HeaderValue(name, value string) postman.HeaderAssertion {
+	return postman.HeaderAssertion{Name: name, Value: value}
+}
+
+// HeaderMatches asserts that a header's value matches the regular expression pattern
+func HeaderMatches(name, pattern string) postman.HeaderAssertion {
+	return postman.HeaderAssertion{Name: name, Pattern: pattern}
+}
+
+// HeaderAbsent asserts that a header is not present
+func HeaderAbsent(name string) postman.HeaderAssertion {
+	return postman.HeaderAssertion{Name: name, Absent: true}
+}
+
+// ExpectHeaders adds header assertions for the named item, checked against every response
+// in addition to any annotated in the collection
+func (t *Tester) ExpectHeaders(name string, assertions ...postman.HeaderAssertion) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.headers == nil {
+		t.headers = map[string][]postman.HeaderAssertion{}
+	}
+
+	t.headers[name] = append(t.headers[name], assertions...)
+}
+
+// checkHeaders checks the actual response against the item's header assertions
+func (t *Tester) checkHeaders(helper *TestHelper, itm *postman.CollectionItem, actual *postman.Response) {
+	t.mu.Lock()
+	assertions := append([]postman.HeaderAssertion{}, t.headers[itm.Name]...)
+	t.mu.Unlock()
+
+	if itm.Gopherman != nil {
+		assertions = append(assertions, itm.Gopherman.Headers...)
+	}
+
+	for _, a := range assertions {
+		helper.AssertHeader(actual, a)
+	}
+}
+
+// AssertHeader adds an error if the actual response's headers don't satisfy a
+func (t *TestHelper) AssertHeader(actual *postman.Response, a postman.HeaderAssertion) bool {
+	header := actual.HTTPHeader()
+	values, ok := header[http.CanonicalHeaderKey(a.Name)]
+
+	switch {
+	case a.Absent && ok:
+		t.Error(fmt.Errorf("expected no header %s, got %s", a.Name, values[0]))
+	case a.Absent:
+		return true
+	case !ok:
+		t.Error(fmt.Errorf("expected header %s to be present", a.Name))
+	case a.Value != "" && !containsString(values, a.Value):
+		t.Error(fmt.Errorf("expected header %s to be %s, got %s", a.Name, a.Value, values[0]))
+	case a.Pattern != "":
+		re, err := regexp.Compile(a.Pattern)
+		if err != nil {
+			t.Error(fmt.Errorf("invalid pattern %s for header %s: %s", a.Pattern, a.Name, err))
+			return false
+		}
+
+		for _, v := range values {
+			if re.MatchString(v) {
+				return true
+			}
+		}
+
+		t.Error(fmt.Errorf("expected header %s to match %s, got %s", a.Name, a.Pattern, values[0]))
+	default:
+		return true
+	}
+
+	return false
+}
+
+// CompareHeaders checks that every header of the expected example is in the actual response
+// with the same value, except VolatileHeaders and t.IgnoreHeaders. Extra actual headers are allowed
+func (t *TestHelper) CompareHeaders(expected, actual *postman.Response) bool {
+	errs := headerErrors(expected, actual, t.IgnoreHeaders)
+	for _, err := range errs {
+		t.Error(err)
+	}
+
+	return len(errs) == 0
+}
+
+// headerErrors returns an error for each expected header missing from or different in actual,
+// except VolatileHeaders and ignore
+func headerErrors(expected, actual *postman.Response, ignore []string) []error {
+	ignored := map[string]bool{}
+	for _, name := range append(append([]string{}, VolatileHeaders...), ignore...) {
+		ignored[http.CanonicalHeaderKey(name)] = true
+	}
+
+	actHeader := actual.HTTPHeader()
+	errs := []error{}
+
+	for _, h := range postman.HeadersFromHTTP(expected.HTTPHeader()) {
+		if ignored[h.Key] {
+			continue
+		}
+
+		values, present := actHeader[h.Key]
+		switch {
+		case !present:
+			errs = append(errs, fmt.Errorf("expected header %s: %s, got none", h.Key, h.Value))
+		case !containsString(values, h.Value):
+			errs = append(errs, fmt.Errorf("expected header %s: %s, got %s", h.Key, h.Value, values[0]))
+		}
+	}
+
+	return errs
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
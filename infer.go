@@ -0,0 +1,216 @@
+package gopherman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cohix/gopherman/jsonschema"
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// EndpointSchemas are the schemas inferred for one endpoint from every recorded request to it
+type EndpointSchemas struct {
+	Method string
+	// Path is the request path with IDs replaced, e.g. /users/{id}
+	Path string
+	// Items is how many items were requests to the endpoint
+	Items int
+
+	// Request is the schema of JSON request bodies, nil if there were none
+	Request *jsonschema.Schema
+	// Responses are the schemas of JSON response bodies by status code
+	Responses map[int]*jsonschema.Schema
+	// Statuses are the status codes of every recorded response, sorted, including those without JSON bodies
+	Statuses []int
+}
+
+var (
+	idSegmentPattern  = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+	unsafeFilePattern = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// InferSchemas infers JSON Schemas for the requests and responses of every endpoint in the
+// collections, such as those written by RequestRecorder. Items are grouped into endpoints by
+// method and path, with numeric, UUID and hex path segments treated as IDs. Bodies that
+// aren't JSON are ignored
+func InferSchemas(collections ...*postman.Collection) []EndpointSchemas {
+	type inferrers struct {
+		endpoint  EndpointSchemas
+		request   *jsonschema.Inferrer
+		responses map[int]*jsonschema.Inferrer
+		statuses  map[int]bool
+	}
+
+	byKey := map[string]*inferrers{}
+	order := []string{}
+
+	for _, collection := range collections {
+		collection.Walk(func(folders []string, itm *postman.CollectionItem) {
+			method := strings.ToUpper(itm.Request.Method)
+			path := endpointPath(itm.Request.URL.Raw)
+			key := method + " " + path
+
+			inf, ok := byKey[key]
+			if !ok {
+				inf = &inferrers{
+					endpoint:  EndpointSchemas{Method: method, Path: path},
+					request:   jsonschema.NewInferrer(),
+					responses: map[int]*jsonschema.Inferrer{},
+					statuses:  map[int]bool{},
+				}
+
+				byKey[key] = inf
+				order = append(order, key)
+			}
+
+			inf.endpoint.Items++
+
+			if body := strings.TrimSpace(itm.Request.Body.Raw); body != "" {
+				// bodies that aren't JSON are skipped
+				inf.request.Add([]byte(body))
+			}
+
+			for _, resp := range itm.Response {
+				inf.statuses[resp.Status] = true
+
+				body := strings.TrimSpace(resp.Raw)
+				if body == "" {
+					continue
+				}
+
+				if _, ok := inf.responses[resp.Status]; !ok {
+					inf.responses[resp.Status] = jsonschema.NewInferrer()
+				}
+
+				inf.responses[resp.Status].Add([]byte(body))
+			}
+		})
+	}
+
+	endpoints := []EndpointSchemas{}
+
+	for _, key := range order {
+		inf := byKey[key]
+		endpoint := inf.endpoint
+		endpoint.Responses = map[int]*jsonschema.Schema{}
+
+		if inf.request.Samples > 0 {
+			endpoint.Request = inf.request.Schema()
+		}
+
+		for status, in := range inf.responses {
+			if in.Samples > 0 {
+				endpoint.Responses[status] = in.Schema()
+			}
+		}
+
+		for status := range inf.statuses {
+			endpoint.Statuses = append(endpoint.Statuses, status)
+		}
+
+		sort.Ints(endpoint.Statuses)
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
+
+// endpointPath returns a request URL's path without its host or query, with IDs replaced by {id}
+func endpointPath(raw string) string {
+	path := raw
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+	}
+
+	// anything before the first slash is the host, such as {{BaseUrl}}:{{Port}}
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[i:]
+	} else {
+		path = "/"
+	}
+
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if idSegmentPattern.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// FileName returns the base name used for the endpoint's schema files, e.g. get_users_id
+func (e *EndpointSchemas) FileName() string {
+	name := unsafeFilePattern.ReplaceAllString(strings.ToLower(e.Method+" "+e.Path), "_")
+	name = strings.Trim(name, "_")
+
+	if name == strings.ToLower(e.Method) {
+		name += "_root"
+	}
+
+	return name
+}
+
+// WriteSchemas writes each endpoint's schemas to dir, as <name>.request.json and
+// <name>.<status>.json (see FileName), returning the paths of the files written
+func WriteSchemas(dir string, endpoints []EndpointSchemas) ([]string, error) {
+	written := []string{}
+
+	write := func(name string, schema *jsonschema.Schema) error {
+		data, err := json.MarshalIndent(schema, "", "\t")
+		if err != nil {
+			return errors.Wrap(err, "failed to Marshal schema")
+		}
+
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return errors.Wrap(err, "failed to write schema")
+		}
+
+		written = append(written, path)
+
+		return nil
+	}
+
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		name := endpoint.FileName()
+
+		if endpoint.Request != nil {
+			if err := write(name+".request.json", endpoint.Request); err != nil {
+				return written, err
+			}
+		}
+
+		statuses := []int{}
+		for status := range endpoint.Responses {
+			statuses = append(statuses, status)
+		}
+
+		sort.Ints(statuses)
+
+		for _, status := range statuses {
+			if err := write(fmt.Sprintf("%s.%d.json", name, status), endpoint.Responses[status]); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
@@ -0,0 +1,104 @@
+package gopherman
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+)
+
+// inProcessURL is the base URL of requests sent to an in-process handler
+const inProcessURL = "http://localhost"
+
+// NewTesterWithHandler loads collections like NewTesterWithCollection, but runs them against
+// handler in-process, with no server, port or environment host variables needed
+func NewTesterWithHandler(handler http.Handler, path string, envFile string, files ...string) (*Tester, error) {
+	tester, err := NewTesterWithCollection(path, envFile, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	tester.Client = &http.Client{Transport: &handlerTransport{handler: handler}}
+	tester.Target = &Target{BaseURL: inProcessURL}
+
+	return tester, nil
+}
+
+// NewTesterWithServer loads collections like NewTesterWithCollection, but runs them against
+// srv using its own client, so TLS servers work without any further configuration
+func NewTesterWithServer(srv *httptest.Server, path string, envFile string, files ...string) (*Tester, error) {
+	tester, err := NewTesterWithCollection(path, envFile, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	tester.Client = srv.Client()
+	tester.Target = &Target{BaseURL: srv.URL}
+
+	return tester, nil
+}
+
+// handlerTransport is an http.RoundTripper that serves requests with a handler directly
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// RoundTrip serves req with the handler and returns the recorded response
+func (h *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the handler should see the request as a server would
+	srvReq := req.Clone(req.Context())
+	srvReq.RequestURI = req.URL.RequestURI()
+	srvReq.RemoteAddr = "127.0.0.1:0"
+
+	if srvReq.Host == "" {
+		srvReq.Host = req.URL.Host
+	}
+
+	if srvReq.Body == nil {
+		srvReq.Body = http.NoBody
+	}
+
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+
+	var panicked *PanicError
+
+	// serve in the background so that a hung handler can't outlive the request's context
+	go func() {
+		defer close(done)
+
+		defer func() {
+			if r := recover(); r != nil {
+				panicked = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+
+		h.handler.ServeHTTP(rec, srvReq)
+	}()
+
+	select {
+	case <-done:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	if panicked != nil {
+		return nil, panicked
+	}
+
+	resp := rec.Result()
+	resp.Request = req
+
+	return resp, nil
+}
+
+// PanicError is the error for a request whose in-process handler panicked. A real server
+// would have closed the connection instead
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("handler panicked: %v", p.Value)
+}
@@ -0,0 +1,54 @@
+package gopherman
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerTransport(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("X-Host", r.Host)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, "%s %s %s %s", r.Method, r.RequestURI, r.RemoteAddr, body)
+		case "/panic":
+			panic("boom")
+		case "/hang":
+			<-r.Context().Done()
+		}
+	})
+
+	client := &http.Client{Transport: &handlerTransport{handler: handler}}
+
+	resp, err := client.Post(inProcessURL+"/echo?x=1", "text/plain", strings.NewReader("hello"))
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "localhost", resp.Header.Get("X-Host"))
+		assert.Equal(t, "POST /echo?x=1 127.0.0.1:0 hello", string(body))
+	}
+
+	_, err = client.Get(inProcessURL + "/panic")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "handler panicked: boom")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequest(http.MethodGet, inProcessURL+"/hang", nil)
+
+	_, err = client.Do(req.WithContext(ctx))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "context deadline exceeded")
+	}
+}
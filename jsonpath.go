@@ -0,0 +1,263 @@
+package gopherman
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a JSON path: an object key, an array index or a wildcard
+type pathSegment struct {
+	key   string
+	index int
+	kind  segmentKind
+}
+
+type segmentKind int
+
+const (
+	keySegment      segmentKind = iota // .name or ['name']
+	indexSegment                       // [0]
+	anyKeySegment                      // .*
+	anyIndexSegment                    // [*]
+	descendSegment                     // .. (zero or more segments)
+)
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$-]*$`)
+
+func keySeg(key string) pathSegment {
+	return pathSegment{kind: keySegment, key: key}
+}
+
+func indexSeg(index int) pathSegment {
+	return pathSegment{kind: indexSegment, index: index}
+}
+
+func (p pathSegment) String() string {
+	switch p.kind {
+	case indexSegment:
+		return fmt.Sprintf("[%d]", p.index)
+	case anyKeySegment:
+		return ".*"
+	case anyIndexSegment:
+		return "[*]"
+	case descendSegment:
+		return ".."
+	}
+
+	if identPattern.MatchString(p.key) {
+		return "." + p.key
+	}
+
+	return "['" + strings.Replace(p.key, "'", "\\'", -1) + "']"
+}
+
+// formatPath renders segments as a JSON path such as $.items[0].id
+func formatPath(segments []pathSegment) string {
+	path := "$"
+	for _, s := range segments {
+		path += s.String()
+	}
+
+	return path
+}
+
+// parsePath parses a JSON path. The supported subset is the root $, .key, ['key'], [0],
+// and the wildcards .*, [*] and .. (recursive descent), e.g. $..createdAt or $.items[*].id
+func parsePath(path string) ([]pathSegment, error) {
+	segments := []pathSegment{}
+
+	rest := strings.TrimSpace(path)
+	if !strings.HasPrefix(rest, "$") {
+		// a bare path like items[0].id is relative to the root
+		rest = "$." + rest
+	}
+
+	rest = rest[1:]
+
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segments = append(segments, pathSegment{kind: descendSegment})
+			rest = rest[2:]
+
+			if strings.HasPrefix(rest, "[") {
+				continue
+			}
+
+			name, remaining := splitName(rest)
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %s: expected a name after ..", path)
+			}
+
+			segments = append(segments, nameSegment(name))
+			rest = remaining
+
+		case strings.HasPrefix(rest, "."):
+			name, remaining := splitName(rest[1:])
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %s: expected a name after .", path)
+			}
+
+			segments = append(segments, nameSegment(name))
+			rest = remaining
+
+		case strings.HasPrefix(rest, "["):
+			end := closingBracket(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %s: unclosed [", path)
+			}
+
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{kind: anyIndexSegment})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				key := strings.Replace(inner[1:len(inner)-1], "\\"+inner[:1], inner[:1], -1)
+				segments = append(segments, keySeg(key))
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %s: bad index [%s]", path, inner)
+				}
+
+				segments = append(segments, indexSeg(index))
+			}
+
+		default:
+			return nil, fmt.Errorf("invalid path %s: unexpected %q", path, rest[:1])
+		}
+	}
+
+	return segments, nil
+}
+
+func nameSegment(name string) pathSegment {
+	if name == "*" {
+		return pathSegment{kind: anyKeySegment}
+	}
+
+	return keySeg(name)
+}
+
+// splitName splits a dotted name from the rest of a path
+func splitName(str string) (string, string) {
+	end := strings.IndexAny(str, ".[")
+	if end < 0 {
+		return str, ""
+	}
+
+	return str[:end], str[end:]
+}
+
+// closingBracket finds the ] that closes the [ at the start of str, skipping quoted keys
+func closingBracket(str string) int {
+	var quote byte
+
+	for i := 1; i < len(str); i++ {
+		c := str[i]
+
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote == 0 && c == ']':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// matchPath returns true if the concrete path matches pattern
+func matchPath(pattern, path []pathSegment) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	p := pattern[0]
+
+	if p.kind == descendSegment {
+		for skip := 0; skip <= len(path); skip++ {
+			if matchPath(pattern[1:], path[skip:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+
+	s := path[0]
+
+	switch p.kind {
+	case keySegment:
+		if s.kind != keySegment || s.key != p.key {
+			return false
+		}
+	case indexSegment:
+		if s.kind != indexSegment || s.index != p.index {
+			return false
+		}
+	case anyKeySegment:
+		if s.kind != keySegment {
+			return false
+		}
+	case anyIndexSegment:
+		if s.kind != indexSegment {
+			return false
+		}
+	}
+
+	return matchPath(pattern[1:], path[1:])
+}
+
+// lookupPath returns the value in doc at a path without wildcards
+func lookupPath(doc interface{}, segments []pathSegment) (interface{}, bool) {
+	current := doc
+
+	for _, s := range segments {
+		switch s.kind {
+		case keySegment:
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+
+			if current, ok = obj[s.key]; !ok {
+				return nil, false
+			}
+
+		case indexSegment:
+			arr, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+
+			index := s.index
+			if index < 0 {
+				index += len(arr)
+			}
+
+			if index < 0 || index >= len(arr) {
+				return nil, false
+			}
+
+			current = arr[index]
+
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
@@ -0,0 +1,108 @@
+package gopherman
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"$", "$"},
+		{"$.a", "$.a"},
+		{"a.b", "$.a.b"},
+		{"$.a[0].b", "$.a[0].b"},
+		{"$['a b']", "$['a b']"},
+		{`$["a"]`, "$.a"},
+		{`$['it\'s']`, `$['it\'s']`},
+		{"$['a]b']", "$['a]b']"},
+		{"$.items[*].id", "$.items[*].id"},
+		{"$.*", "$.*"},
+		{"$..id", "$...id"},
+		{"$..[0]", "$..[0]"},
+		{"$[-1]", "$[-1]"},
+		{" $.a ", "$.a"},
+	}
+
+	for _, test := range tests {
+		segments, err := parsePath(test.path)
+		if assert.NoError(t, err, test.path) {
+			assert.Equal(t, test.expected, formatPath(segments), test.path)
+		}
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, path := range []string{"$.", "$..", "$[", "$[x]", "$['a'", "$a"} {
+		_, err := parsePath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"$.a", "$.a", true},
+		{"$.a", "$.b", false},
+		{"$.a", "$.a.b", false},
+		{"$.*", "$.a", true},
+		{"$.*", "$[0]", false},
+		{"$[*]", "$[3]", true},
+		{"$[*]", "$.a", false},
+		{"$..id", "$.id", true},
+		{"$..id", "$.a[0].b.id", true},
+		{"$..id", "$.a.idx", false},
+		{"$.a..c", "$.a.b.c", true},
+		{"$.a..c", "$.x.b.c", false},
+		{"$", "$", true},
+		{"$..*", "$.a.b", true},
+	}
+
+	for _, test := range tests {
+		pattern, err := parsePath(test.pattern)
+		assert.NoError(t, err, test.pattern)
+
+		path, err := parsePath(test.path)
+		assert.NoError(t, err, test.path)
+
+		assert.Equal(t, test.match, matchPath(pattern, path), "%s against %s", test.pattern, test.path)
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{"a":{"b":[10,20,{"c":"x"}]},"d e":true}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{"$.a.b[0]", 10.0, true},
+		{"$.a.b[-1].c", "x", true},
+		{"$['d e']", true, true},
+		{"$.a.b[3]", nil, false},
+		{"$.a.x", nil, false},
+		{"$.a.b.c", nil, false},
+		{"$.a[0]", nil, false},
+		{"$.a.b[*]", nil, false},
+	}
+
+	for _, test := range tests {
+		segments, err := parsePath(test.path)
+		assert.NoError(t, err, test.path)
+
+		val, found := lookupPath(doc, segments)
+		assert.Equal(t, test.found, found, test.path)
+		assert.Equal(t, test.expected, val, test.path)
+	}
+}
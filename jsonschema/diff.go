@@ -0,0 +1,160 @@
+package jsonschema
+
+import (
+	"sort"
+)
+
+// Kinds of change found by Diff
+const (
+	PropertyAdded   = "added"
+	PropertyRemoved = "removed"
+	TypeChanged     = "retyped"
+	FormatChanged   = "reformatted"
+	BecameRequired  = "required"
+	BecameOptional  = "optional"
+)
+
+// Change is a difference between two schemas at a path in the documents they describe
+type Change struct {
+	Path string
+	Kind string
+
+	// Old and New are the types at Path, or for FormatChanged the formats
+	Old []string
+	New []string
+
+	// Required is true if an added property is required
+	Required bool
+}
+
+// Diff compares the structure of two schemas, such as those made by Infer, through their
+// type, properties, required, items and format keywords. Other keywords, including $ref, are ignored
+func Diff(before, after *Schema) []Change {
+	changes := []Change{}
+
+	return diffSchemas(changes, "$", before.root, after.root)
+}
+
+func diffSchemas(changes []Change, path string, before, after interface{}) []Change {
+	oldMap, _ := before.(map[string]interface{})
+	newMap, _ := after.(map[string]interface{})
+
+	oldTypes, newTypes := schemaTypes(oldMap), schemaTypes(newMap)
+	if len(oldTypes) > 0 && len(newTypes) > 0 && !sameStrings(oldTypes, newTypes) {
+		changes = append(changes, Change{Path: path, Kind: TypeChanged, Old: oldTypes, New: newTypes})
+	}
+
+	oldFormat, _ := oldMap["format"].(string)
+	newFormat, _ := newMap["format"].(string)
+	if oldFormat != newFormat {
+		changes = append(changes, Change{Path: path, Kind: FormatChanged, Old: nonEmpty(oldFormat), New: nonEmpty(newFormat)})
+	}
+
+	oldProps, _ := oldMap["properties"].(map[string]interface{})
+	newProps, _ := newMap["properties"].(map[string]interface{})
+	oldRequired, newRequired := requiredSet(oldMap), requiredSet(newMap)
+
+	keys := []string{}
+	for key := range oldProps {
+		keys = append(keys, key)
+	}
+
+	for key := range newProps {
+		if _, ok := oldProps[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyed := keyPath(path, key)
+		oldProp, inOld := oldProps[key]
+		newProp, inNew := newProps[key]
+
+		switch {
+		case !inNew:
+			changes = append(changes, Change{Path: keyed, Kind: PropertyRemoved, Old: schemaTypes(asMap(oldProp))})
+		case !inOld:
+			changes = append(changes, Change{Path: keyed, Kind: PropertyAdded, New: schemaTypes(asMap(newProp)), Required: newRequired[key]})
+		default:
+			if newRequired[key] && !oldRequired[key] {
+				changes = append(changes, Change{Path: keyed, Kind: BecameRequired})
+			} else if oldRequired[key] && !newRequired[key] {
+				changes = append(changes, Change{Path: keyed, Kind: BecameOptional})
+			}
+
+			changes = diffSchemas(changes, keyed, oldProp, newProp)
+		}
+	}
+
+	if oldItems, ok := oldMap["items"]; ok {
+		if newItems, ok := newMap["items"]; ok {
+			changes = diffSchemas(changes, path+"[*]", oldItems, newItems)
+		}
+	}
+
+	return changes
+}
+
+// schemaTypes returns the sorted types a schema allows, or nil if it doesn't say
+func schemaTypes(s map[string]interface{}) []string {
+	types := []string{}
+
+	switch t := s["type"].(type) {
+	case string:
+		types = append(types, t)
+	case []interface{}:
+		for _, name := range t {
+			if str, ok := name.(string); ok {
+				types = append(types, str)
+			}
+		}
+	default:
+		return nil
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+func requiredSet(s map[string]interface{}) map[string]bool {
+	set := map[string]bool{}
+
+	list, _ := s["required"].([]interface{})
+	for _, name := range list {
+		if str, ok := name.(string); ok {
+			set[str] = true
+		}
+	}
+
+	return set
+}
+
+func asMap(s interface{}) map[string]interface{} {
+	m, _ := s.(map[string]interface{})
+	return m
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return []string{s}
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
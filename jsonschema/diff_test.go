@@ -0,0 +1,54 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		name    string
+		before  string
+		after   string
+		changes []Change
+	}{
+		{"same", `{"type":"object","properties":{"a":{"type":"string"}},"required":["a"]}`, `{"required":["a"],"properties":{"a":{"type":"string"}},"type":"object"}`, []Change{}},
+		{"added", `{"type":"object","properties":{}}`, `{"type":"object","properties":{"a":{"type":"string"},"b":{"type":["null","integer"]}},"required":["a"]}`, []Change{
+			{Path: "$.a", Kind: PropertyAdded, New: []string{"string"}, Required: true},
+			{Path: "$.b", Kind: PropertyAdded, New: []string{"integer", "null"}},
+		}},
+		{"removed", `{"type":"object","properties":{"a":{"type":"string"}}}`, `{"type":"object"}`, []Change{
+			{Path: "$.a", Kind: PropertyRemoved, Old: []string{"string"}},
+		}},
+		{"retyped", `{"type":"object","properties":{"a":{"type":"integer"}}}`, `{"type":"object","properties":{"a":{"type":["string","integer"]}}}`, []Change{
+			{Path: "$.a", Kind: TypeChanged, Old: []string{"integer"}, New: []string{"integer", "string"}},
+		}},
+		{"untyped", `{"properties":{"a":{}}}`, `{"properties":{"a":{"type":"string"}}}`, []Change{}},
+		{"format", `{"type":"string","format":"uuid"}`, `{"type":"string"}`, []Change{
+			{Path: "$", Kind: FormatChanged, Old: []string{"uuid"}},
+		}},
+		{"required", `{"properties":{"a":{},"b":{}},"required":["a"]}`, `{"properties":{"a":{},"b":{}},"required":["b"]}`, []Change{
+			{Path: "$.a", Kind: BecameOptional},
+			{Path: "$.b", Kind: BecameRequired},
+		}},
+		{"nested", `{"properties":{"user":{"properties":{"first name":{"type":"string"}}}}}`, `{"properties":{"user":{"properties":{"first name":{"type":"number"}}}}}`, []Change{
+			{Path: "$.user['first name']", Kind: TypeChanged, Old: []string{"string"}, New: []string{"number"}},
+		}},
+		{"items", `{"type":"array","items":{"properties":{"id":{"type":"integer"}}}}`, `{"type":"array","items":{"properties":{"id":{"type":"string","format":"uuid"}}}}`, []Change{
+			{Path: "$[*].id", Kind: TypeChanged, Old: []string{"integer"}, New: []string{"string"}},
+			{Path: "$[*].id", Kind: FormatChanged, New: []string{"uuid"}},
+		}},
+		{"boolean schemas", `true`, `{"type":"object"}`, []Change{}},
+	}
+
+	for _, tc := range cases {
+		before, err := Parse([]byte(tc.before))
+		assert.NoError(t, err, tc.name)
+
+		after, err := Parse([]byte(tc.after))
+		assert.NoError(t, err, tc.name)
+
+		assert.Equal(t, tc.changes, Diff(before, after), tc.name)
+	}
+}
@@ -0,0 +1,76 @@
+package jsonschema
+
+import (
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+)
+
+// formats are the values of the format keyword that are checked. Other formats are accepted without checking
+var formats = map[string]func(string) bool{
+	"date-time": isDateTime,
+	"date":      isDate,
+	"time":      isTime,
+	"email":     isEmail,
+	"hostname":  isHostname,
+	"ipv4":      isIPv4,
+	"ipv6":      isIPv6,
+	"uri":       isURI,
+	"uuid":      isUUID,
+	"regex":     isRegex,
+}
+
+func isDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+func isTime(s string) bool {
+	_, err := time.Parse("15:04:05.999999999Z07:00", s)
+	return err == nil
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func isHostname(s string) bool {
+	return len(s) <= 253 && hostnamePattern.MatchString(s)
+}
+
+func isIPv4(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+}
+
+func isIPv6(s string) bool {
+	return net.ParseIP(s) != nil && strings.Contains(s, ":")
+}
+
+func isURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != ""
+}
+
+func isUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+func isRegex(s string) bool {
+	_, err := regexp.Compile(s)
+	return err == nil
+}
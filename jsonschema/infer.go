@@ -0,0 +1,215 @@
+package jsonschema
+
+import (
+	"sort"
+	"strings"
+)
+
+// Draft07 is the $schema URI of the schemas produced by Inferrer
+const Draft07 = "http://json-schema.org/draft-07/schema#"
+
+// Inferrer learns a schema from sample JSON documents. Types seen in different samples
+// are merged, properties missing from any sample are optional, and string formats are
+// kept when every sample of a string has the same one
+type Inferrer struct {
+	root    *shape
+	Samples int
+}
+
+// NewInferrer returns an Inferrer with no samples
+func NewInferrer() *Inferrer {
+	return &Inferrer{root: newShape()}
+}
+
+// Add adds a sample JSON document
+func (in *Inferrer) Add(data []byte) error {
+	value, err := decode(data)
+	if err != nil {
+		return err
+	}
+
+	in.AddValue(value)
+
+	return nil
+}
+
+// AddValue adds a sample value decoded from JSON
+func (in *Inferrer) AddValue(value interface{}) {
+	in.root.add(value)
+	in.Samples++
+}
+
+// Schema returns a schema that every sample added so far conforms to
+func (in *Inferrer) Schema() *Schema {
+	root := in.root.schema()
+	root["$schema"] = Draft07
+
+	return &Schema{root: root}
+}
+
+// Infer returns a schema that every sample conforms to
+func Infer(samples ...[]byte) (*Schema, error) {
+	in := NewInferrer()
+
+	for _, sample := range samples {
+		if err := in.Add(sample); err != nil {
+			return nil, err
+		}
+	}
+
+	return in.Schema(), nil
+}
+
+// DetectFormat returns the format of a string, such as uuid, date-time or email, or "" if it has none
+func DetectFormat(s string) string {
+	switch {
+	case isUUID(s):
+		return "uuid"
+	case isDateTime(s):
+		return "date-time"
+	case isDate(s):
+		return "date"
+	case isEmail(s):
+		return "email"
+	case isIPv4(s):
+		return "ipv4"
+	case isIPv6(s):
+		return "ipv6"
+	case strings.Contains(s, "://") && isURI(s):
+		return "uri"
+	}
+
+	return ""
+}
+
+// shape accumulates what has been seen at one place in the sample documents
+type shape struct {
+	types map[string]bool
+
+	// objects is how many objects were seen, and properties what was seen in them
+	objects    int
+	properties map[string]*shape
+	// seen counts the objects each property appeared in
+	seen map[string]int
+
+	items *shape
+
+	strings int
+	format  string
+}
+
+func newShape() *shape {
+	return &shape{
+		types:      map[string]bool{},
+		properties: map[string]*shape{},
+		seen:       map[string]int{},
+	}
+}
+
+func (s *shape) add(value interface{}) {
+	s.types[typeOf(value)] = true
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.objects++
+
+		for key, val := range v {
+			prop, ok := s.properties[key]
+			if !ok {
+				prop = newShape()
+				s.properties[key] = prop
+			}
+
+			prop.add(val)
+			s.seen[key]++
+		}
+	case []interface{}:
+		for _, val := range v {
+			if s.items == nil {
+				s.items = newShape()
+			}
+
+			s.items.add(val)
+		}
+	case string:
+		format := DetectFormat(v)
+		if s.strings == 0 {
+			s.format = format
+		} else if s.format != format {
+			s.format = ""
+		}
+
+		s.strings++
+	}
+}
+
+func (s *shape) schema() map[string]interface{} {
+	schema := map[string]interface{}{}
+
+	types := []string{}
+	for t := range s.types {
+		// an integer is a number, so a number type covers both
+		if t == "integer" && s.types["number"] {
+			continue
+		}
+
+		types = append(types, t)
+	}
+
+	sort.Strings(types)
+
+	switch len(types) {
+	case 0:
+		// nothing was seen here, e.g. the items of arrays that were always empty
+		return schema
+	case 1:
+		schema["type"] = types[0]
+	default:
+		list := []interface{}{}
+		for _, t := range types {
+			list = append(list, t)
+		}
+
+		schema["type"] = list
+	}
+
+	if s.types["object"] {
+		properties := map[string]interface{}{}
+		required := []interface{}{}
+
+		for _, key := range sortedShapeKeys(s.properties) {
+			properties[key] = s.properties[key].schema()
+
+			if s.seen[key] == s.objects {
+				required = append(required, key)
+			}
+		}
+
+		schema["properties"] = properties
+
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+
+	if s.types["array"] && s.items != nil {
+		schema["items"] = s.items.schema()
+	}
+
+	if s.types["string"] && s.format != "" {
+		schema["format"] = s.format
+	}
+
+	return schema
+}
+
+func sortedShapeKeys(m map[string]*shape) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,167 @@
+// Package jsonschema validates JSON documents against JSON Schemas. It implements the
+// commonly used keywords of draft-07, with $ref limited to references within the same schema
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Schema is a parsed JSON Schema
+type Schema struct {
+	// root is the schema document, either a bool or a map[string]interface{} with json.Number numbers
+	root interface{}
+}
+
+// ValidationError describes a way a document doesn't conform to a schema and where in the document it is
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (v ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// Parse parses a JSON Schema
+func Parse(data []byte) (*Schema, error) {
+	root, err := decode(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse schema")
+	}
+
+	switch root.(type) {
+	case bool, map[string]interface{}:
+	default:
+		return nil, errors.New("schema must be an object or a boolean")
+	}
+
+	if err := checkPatterns(root); err != nil {
+		return nil, err
+	}
+
+	return &Schema{root: root}, nil
+}
+
+// ParseFile parses a JSON Schema file
+func ParseFile(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	return schema, nil
+}
+
+// MarshalJSON returns the schema document
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.root)
+}
+
+// Validate checks a JSON document against the schema, returning every violation found
+func (s *Schema) Validate(data []byte) []ValidationError {
+	value, err := decode(data)
+	if err != nil {
+		return []ValidationError{{Path: "$", Message: "invalid JSON: " + err.Error()}}
+	}
+
+	return s.ValidateValue(value)
+}
+
+// ValidateValue checks a value decoded from JSON against the schema, returning every violation found.
+// Numbers may be json.Number or float64
+func (s *Schema) ValidateValue(value interface{}) []ValidationError {
+	v := validator{root: s.root}
+
+	errs := v.validate(s.root, value, "$")
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+
+	return value, nil
+}
+
+// checkPatterns makes sure every pattern in the schema compiles, so that bad patterns are found up front
+func checkPatterns(schema interface{}) error {
+	switch s := schema.(type) {
+	case map[string]interface{}:
+		for key, val := range s {
+			if key == "pattern" {
+				if pattern, ok := val.(string); ok {
+					if _, err := regexp.Compile(pattern); err != nil {
+						return errors.Wrapf(err, "invalid pattern %s", pattern)
+					}
+				}
+			}
+
+			if key == "patternProperties" {
+				if props, ok := val.(map[string]interface{}); ok {
+					for pattern := range props {
+						if _, err := regexp.Compile(pattern); err != nil {
+							return errors.Wrapf(err, "invalid pattern %s", pattern)
+						}
+					}
+				}
+			}
+
+			if key == "enum" || key == "const" || key == "default" || key == "examples" {
+				continue
+			}
+
+			if err := checkPatterns(val); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, val := range s {
+			if err := checkPatterns(val); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$-]*$`)
+
+// keyPath appends an object key to a JSON path
+func keyPath(path, key string) string {
+	if identPattern.MatchString(key) {
+		return path + "." + key
+	}
+
+	return path + "['" + strings.Replace(key, "'", "\\'", -1) + "']"
+}
+
+// indexPath appends an array index to a JSON path
+func indexPath(path string, index int) string {
+	return fmt.Sprintf("%s[%d]", path, index)
+}
@@ -0,0 +1,564 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRefDepth stops validation following $refs that loop without consuming any of the document
+const maxRefDepth = 64
+
+// validator checks values against the subschemas of root
+type validator struct {
+	root     interface{}
+	refDepth int
+}
+
+func (v *validator) validate(schema interface{}, value interface{}, path string) []ValidationError {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return []ValidationError{{Path: path, Message: "no value is allowed here"}}
+		}
+
+		return nil
+	case map[string]interface{}:
+		return v.validateObject(s, value, path)
+	}
+
+	return []ValidationError{{Path: path, Message: fmt.Sprintf("invalid schema %v", schema)}}
+}
+
+func (v *validator) validateObject(s map[string]interface{}, value interface{}, path string) []ValidationError {
+	// in draft-07 a $ref replaces every other keyword next to it
+	if ref, ok := s["$ref"].(string); ok {
+		return v.validateRef(ref, value, path)
+	}
+
+	errs := []ValidationError{}
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, value) {
+		add("expected %s, got %s", describeType(t), typeOf(value))
+		// the remaining keywords would only repeat the type mismatch
+		return errs
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			add("%s is not one of %s", jsonText(value), jsonText(enum))
+		}
+	}
+
+	if c, ok := s["const"]; ok && !equal(c, value) {
+		add("expected %s, got %s", jsonText(c), jsonText(value))
+	}
+
+	switch val := value.(type) {
+	case string:
+		v.validateString(s, val, add)
+	case []interface{}:
+		errs = append(errs, v.validateArray(s, val, path, add)...)
+	case map[string]interface{}:
+		errs = append(errs, v.validateProperties(s, val, path, add)...)
+	default:
+		if n, ok := number(value); ok {
+			validateNumber(s, n, add)
+		}
+	}
+
+	errs = append(errs, v.validateCombinators(s, value, path, add)...)
+
+	return errs
+}
+
+func (v *validator) validateRef(ref string, value interface{}, path string) []ValidationError {
+	target, err := v.resolve(ref)
+	if err != nil {
+		return []ValidationError{{Path: path, Message: err.Error()}}
+	}
+
+	if v.refDepth >= maxRefDepth {
+		return []ValidationError{{Path: path, Message: fmt.Sprintf("$ref %s is too deeply nested", ref)}}
+	}
+
+	v.refDepth++
+	defer func() { v.refDepth-- }()
+
+	return v.validate(target, value, path)
+}
+
+// resolve finds the subschema referenced by a JSON pointer such as #/definitions/user
+func (v *validator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("can't resolve $ref %s: only references within the schema are supported", ref)
+	}
+
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %s", ref)
+	}
+
+	target := v.root
+	if pointer == "" {
+		return target, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("can't resolve $ref %s: only JSON pointers are supported", ref)
+	}
+
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+
+		switch t := target.(type) {
+		case map[string]interface{}:
+			next, ok := t[token]
+			if !ok {
+				return nil, fmt.Errorf("can't resolve $ref %s", ref)
+			}
+
+			target = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, fmt.Errorf("can't resolve $ref %s", ref)
+			}
+
+			target = t[i]
+		default:
+			return nil, fmt.Errorf("can't resolve $ref %s", ref)
+		}
+	}
+
+	return target, nil
+}
+
+func validateNumber(s map[string]interface{}, n *big.Rat, add func(string, ...interface{})) {
+	if m, ok := number(s["multipleOf"]); ok && m.Sign() > 0 {
+		if !new(big.Rat).Quo(n, m).IsInt() {
+			add("%s is not a multiple of %s", numText(n), numText(m))
+		}
+	}
+
+	if max, ok := number(s["maximum"]); ok && n.Cmp(max) > 0 {
+		add("%s is greater than the maximum %s", numText(n), numText(max))
+	}
+
+	if max, ok := number(s["exclusiveMaximum"]); ok && n.Cmp(max) >= 0 {
+		add("%s is not less than %s", numText(n), numText(max))
+	}
+
+	if min, ok := number(s["minimum"]); ok && n.Cmp(min) < 0 {
+		add("%s is less than the minimum %s", numText(n), numText(min))
+	}
+
+	if min, ok := number(s["exclusiveMinimum"]); ok && n.Cmp(min) <= 0 {
+		add("%s is not greater than %s", numText(n), numText(min))
+	}
+}
+
+func (v *validator) validateString(s map[string]interface{}, str string, add func(string, ...interface{})) {
+	length := utf8.RuneCountInString(str)
+
+	if max, ok := integer(s["maxLength"]); ok && length > max {
+		add("string is longer than %d characters", max)
+	}
+
+	if min, ok := integer(s["minLength"]); ok && length < min {
+		add("string is shorter than %d characters", min)
+	}
+
+	if pattern, ok := s["pattern"].(string); ok {
+		// patterns were checked when the schema was parsed
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(str) {
+			add("%s doesn't match pattern %s", jsonText(str), pattern)
+		}
+	}
+
+	if format, ok := s["format"].(string); ok {
+		if check, ok := formats[format]; ok && !check(str) {
+			add("%s is not a valid %s", jsonText(str), format)
+		}
+	}
+}
+
+func (v *validator) validateArray(s map[string]interface{}, arr []interface{}, path string, add func(string, ...interface{})) []ValidationError {
+	errs := []ValidationError{}
+
+	if max, ok := integer(s["maxItems"]); ok && len(arr) > max {
+		add("array has more than %d items", max)
+	}
+
+	if min, ok := integer(s["minItems"]); ok && len(arr) < min {
+		add("array has fewer than %d items", min)
+	}
+
+	if unique, _ := s["uniqueItems"].(bool); unique {
+	outer:
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					add("items %d and %d are equal", j, i)
+					break outer
+				}
+			}
+		}
+	}
+
+	switch items := s["items"].(type) {
+	case []interface{}:
+		for i := range arr {
+			if i < len(items) {
+				errs = append(errs, v.validate(items[i], arr[i], indexPath(path, i))...)
+			} else if additional, ok := s["additionalItems"]; ok {
+				errs = append(errs, v.validate(additional, arr[i], indexPath(path, i))...)
+			}
+		}
+	case nil:
+	default:
+		for i := range arr {
+			errs = append(errs, v.validate(items, arr[i], indexPath(path, i))...)
+		}
+	}
+
+	if contains, ok := s["contains"]; ok {
+		found := false
+		for i := range arr {
+			if len(v.validate(contains, arr[i], indexPath(path, i))) == 0 {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			add("array doesn't contain a matching item")
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) validateProperties(s map[string]interface{}, obj map[string]interface{}, path string, add func(string, ...interface{})) []ValidationError {
+	errs := []ValidationError{}
+
+	if max, ok := integer(s["maxProperties"]); ok && len(obj) > max {
+		add("object has more than %d properties", max)
+	}
+
+	if min, ok := integer(s["minProperties"]); ok && len(obj) < min {
+		add("object has fewer than %d properties", min)
+	}
+
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, ok := obj[name]; !ok {
+					errs = append(errs, ValidationError{Path: keyPath(path, name), Message: "required property is missing"})
+				}
+			}
+		}
+	}
+
+	props, _ := s["properties"].(map[string]interface{})
+	patternProps, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+
+	for _, key := range sortedKeys(obj) {
+		val := obj[key]
+		keyed := keyPath(path, key)
+		matched := false
+
+		if prop, ok := props[key]; ok {
+			matched = true
+			errs = append(errs, v.validate(prop, val, keyed)...)
+		}
+
+		for pattern, prop := range patternProps {
+			if re, err := regexp.Compile(pattern); err == nil && re.MatchString(key) {
+				matched = true
+				errs = append(errs, v.validate(prop, val, keyed)...)
+			}
+		}
+
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				errs = append(errs, ValidationError{Path: keyed, Message: "additional property is not allowed"})
+			} else {
+				errs = append(errs, v.validate(additional, val, keyed)...)
+			}
+		}
+
+		if names, ok := s["propertyNames"]; ok {
+			for _, e := range v.validate(names, key, keyed) {
+				errs = append(errs, ValidationError{Path: keyed, Message: "property name " + e.Message})
+			}
+		}
+	}
+
+	if deps, ok := s["dependencies"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(deps) {
+			if _, ok := obj[key]; !ok {
+				continue
+			}
+
+			if names, ok := deps[key].([]interface{}); ok {
+				for _, n := range names {
+					if name, ok := n.(string); ok {
+						if _, ok := obj[name]; !ok {
+							errs = append(errs, ValidationError{Path: keyPath(path, name), Message: fmt.Sprintf("property is required when %s is present", key)})
+						}
+					}
+				}
+			} else {
+				errs = append(errs, v.validate(deps[key], obj, path)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) validateCombinators(s map[string]interface{}, value interface{}, path string, add func(string, ...interface{})) []ValidationError {
+	errs := []ValidationError{}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, v.validate(sub, value, path)...)
+		}
+	}
+
+	if any, ok := s["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range any {
+			if len(v.validate(sub, value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			add("value doesn't match any of the anyOf schemas")
+		}
+	}
+
+	if one, ok := s["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range one {
+			if len(v.validate(sub, value, path)) == 0 {
+				matches++
+			}
+		}
+
+		if matches != 1 {
+			add("value matches %d of the oneOf schemas, expected exactly 1", matches)
+		}
+	}
+
+	if not, ok := s["not"]; ok && len(v.validate(not, value, path)) == 0 {
+		add("value must not match the not schema")
+	}
+
+	if cond, ok := s["if"]; ok {
+		if len(v.validate(cond, value, path)) == 0 {
+			if then, ok := s["then"]; ok {
+				errs = append(errs, v.validate(then, value, path)...)
+			}
+		} else if els, ok := s["else"]; ok {
+			errs = append(errs, v.validate(els, value, path)...)
+		}
+	}
+
+	return errs
+}
+
+// matchesType checks value against a type keyword, which is a type name or a list of them
+func matchesType(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return isType(t, value)
+	case []interface{}:
+		for _, name := range t {
+			if n, ok := name.(string); ok && isType(n, value) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func isType(name string, value interface{}) bool {
+	actual := typeOf(value)
+
+	switch {
+	case name == actual:
+		return true
+	case name == "number" && actual == "integer":
+		return true
+	}
+
+	return false
+}
+
+func describeType(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := []string{}
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+
+		return strings.Join(names, " or ")
+	}
+
+	return fmt.Sprint(t)
+}
+
+// typeOf returns the JSON Schema type of a value, with integers distinguished from other numbers
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	if n, ok := number(value); ok {
+		if n.IsInt() {
+			return "integer"
+		}
+
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// number converts a JSON number to an exact rational, so that comparisons don't lose precision
+func number(value interface{}) (*big.Rat, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(string(n))
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(n) == nil {
+			return nil, false
+		}
+
+		return r, true
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	}
+
+	return nil, false
+}
+
+// numText formats a number for messages
+func numText(n *big.Rat) string {
+	if n.IsInt() {
+		return n.Num().String()
+	}
+
+	f, _ := n.Float64()
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// integer converts a schema keyword's value to an int, if it is a non-negative integer
+func integer(value interface{}) (int, bool) {
+	n, ok := number(value)
+	if !ok || !n.IsInt() || n.Sign() < 0 || !n.Num().IsInt64() {
+		return 0, false
+	}
+
+	return int(n.Num().Int64()), true
+}
+
+// equal compares two JSON values, treating numbers as equal when their values are
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x.Cmp(y) == 0
+	}
+
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for k := range x {
+			if _, ok := y[k]; !ok || !equal(x[k], y[k]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return a == b
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// jsonText renders a value as JSON for messages, truncating long values
+func jsonText(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	if len(data) > 64 {
+		return string(data[:61]) + "..."
+	}
+
+	return string(data)
+}
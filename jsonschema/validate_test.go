@@ -0,0 +1,114 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		doc    string
+		errs   []string
+	}{
+		{"true schema", `true`, `{"a":1}`, nil},
+		{"false schema", `false`, `1`, []string{"$: no value is allowed here"}},
+		{"type", `{"type":"string"}`, `1`, []string{"$: expected string, got integer"}},
+		{"type list", `{"type":["string","null"]}`, `null`, nil},
+		{"integer is a number", `{"type":"number"}`, `3`, nil},
+		{"float is not an integer", `{"type":"integer"}`, `3.5`, []string{"$: expected integer, got number"}},
+		{"integral float is an integer", `{"type":"integer"}`, `3.0`, nil},
+		{"enum", `{"enum":["a","b"]}`, `"c"`, []string{`$: "c" is not one of ["a","b"]`}},
+		{"enum numbers compare by value", `{"enum":[1,2]}`, `1.0`, nil},
+		{"const", `{"const":{"a":[1]}}`, `{"a":[2]}`, []string{`$: expected {"a":[1]}, got {"a":[2]}`}},
+		{"multipleOf", `{"multipleOf":0.1}`, `0.3`, nil},
+		{"not multipleOf", `{"multipleOf":2}`, `3`, []string{"$: 3 is not a multiple of 2"}},
+		{"maximum", `{"maximum":5}`, `6`, []string{"$: 6 is greater than the maximum 5"}},
+		{"exclusiveMaximum", `{"exclusiveMaximum":5}`, `5`, []string{"$: 5 is not less than 5"}},
+		{"minimum", `{"minimum":5}`, `4.5`, []string{"$: 4.5 is less than the minimum 5"}},
+		{"exclusiveMinimum", `{"exclusiveMinimum":5}`, `5`, []string{"$: 5 is not greater than 5"}},
+		{"maxLength counts characters", `{"maxLength":2}`, `"éé"`, nil},
+		{"maxLength", `{"maxLength":2}`, `"abc"`, []string{"$: string is longer than 2 characters"}},
+		{"minLength", `{"minLength":2}`, `"a"`, []string{"$: string is shorter than 2 characters"}},
+		{"pattern", `{"pattern":"^a+$"}`, `"ab"`, []string{`$: "ab" doesn't match pattern ^a+$`}},
+		{"format", `{"format":"uuid"}`, `"nope"`, []string{`$: "nope" is not a valid uuid`}},
+		{"valid format", `{"format":"date-time"}`, `"2018-01-02T03:04:05Z"`, nil},
+		{"unknown format", `{"format":"shoe-size"}`, `"12"`, nil},
+		{"keywords for other types", `{"minLength":5,"minimum":5}`, `true`, nil},
+		{"maxItems", `{"maxItems":1}`, `[1,2]`, []string{"$: array has more than 1 items"}},
+		{"minItems", `{"minItems":3}`, `[1,2]`, []string{"$: array has fewer than 3 items"}},
+		{"uniqueItems", `{"uniqueItems":true}`, `[1,{"a":1},{"a":1}]`, []string{"$: items 1 and 2 are equal"}},
+		{"items", `{"items":{"type":"integer"}}`, `[1,"a",2,"b"]`, []string{"$[1]: expected integer, got string", "$[3]: expected integer, got string"}},
+		{"tuple items", `{"items":[{"type":"integer"},{"type":"string"}],"additionalItems":false}`, `[1,"a",true]`, []string{"$[2]: no value is allowed here"}},
+		{"contains", `{"contains":{"const":2}}`, `[1,3]`, []string{"$: array doesn't contain a matching item"}},
+		{"maxProperties", `{"maxProperties":1}`, `{"a":1,"b":2}`, []string{"$: object has more than 1 properties"}},
+		{"minProperties", `{"minProperties":1}`, `{}`, []string{"$: object has fewer than 1 properties"}},
+		{"required", `{"required":["a","b c"]}`, `{"a":1}`, []string{"$['b c']: required property is missing"}},
+		{"properties", `{"properties":{"a":{"type":"string"}}}`, `{"a":1,"b":1}`, []string{"$.a: expected string, got integer"}},
+		{"patternProperties", `{"patternProperties":{"^x-":{"type":"string"}}}`, `{"x-a":1,"y":1}`, []string{"$.x-a: expected string, got integer"}},
+		{"additionalProperties false", `{"properties":{"a":{}},"additionalProperties":false}`, `{"a":1,"b":2}`, []string{"$.b: additional property is not allowed"}},
+		{"additionalProperties schema", `{"additionalProperties":{"type":"integer"}}`, `{"a":1,"b":"2"}`, []string{"$.b: expected integer, got string"}},
+		{"propertyNames", `{"propertyNames":{"maxLength":1}}`, `{"ab":1}`, []string{"$.ab: property name string is longer than 1 characters"}},
+		{"property dependencies", `{"dependencies":{"a":["b"]}}`, `{"a":1}`, []string{"$.b: property is required when a is present"}},
+		{"schema dependencies", `{"dependencies":{"a":{"required":["c"]}}}`, `{"a":1}`, []string{"$.c: required property is missing"}},
+		{"allOf", `{"allOf":[{"minimum":1},{"maximum":2}]}`, `3`, []string{"$: 3 is greater than the maximum 2"}},
+		{"anyOf", `{"anyOf":[{"type":"string"},{"type":"null"}]}`, `1`, []string{"$: value doesn't match any of the anyOf schemas"}},
+		{"oneOf none", `{"oneOf":[{"type":"string"},{"type":"null"}]}`, `1`, []string{"$: value matches 0 of the oneOf schemas, expected exactly 1"}},
+		{"oneOf several", `{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1`, []string{"$: value matches 2 of the oneOf schemas, expected exactly 1"}},
+		{"not", `{"not":{"type":"null"}}`, `null`, []string{"$: value must not match the not schema"}},
+		{"then", `{"if":{"required":["a"]},"then":{"required":["b"]},"else":{"required":["c"]}}`, `{"a":1}`, []string{"$.b: required property is missing"}},
+		{"else", `{"if":{"required":["a"]},"then":{"required":["b"]},"else":{"required":["c"]}}`, `{}`, []string{"$.c: required property is missing"}},
+		{"ref", `{"definitions":{"id":{"type":"integer"}},"properties":{"id":{"$ref":"#/definitions/id"}}}`, `{"id":"1"}`, []string{"$.id: expected integer, got string"}},
+		{"escaped ref", `{"definitions":{"a/b":{"type":"integer"}},"$ref":"#/definitions/a~1b"}`, `"1"`, []string{"$: expected integer, got string"}},
+		{"recursive ref", `{"properties":{"child":{"$ref":"#"}},"required":["name"]}`, `{"name":"a","child":{"name":"b","child":{}}}`, []string{"$.child.child.name: required property is missing"}},
+		{"unresolved ref", `{"$ref":"#/definitions/missing"}`, `1`, []string{"$: can't resolve $ref #/definitions/missing"}},
+		{"remote ref", `{"$ref":"http://example.com/schema"}`, `1`, []string{"$: can't resolve $ref http://example.com/schema: only references within the schema are supported"}},
+		{"looping ref", `{"definitions":{"a":{"$ref":"#/definitions/a"}},"$ref":"#/definitions/a"}`, `1`, []string{"$: $ref #/definitions/a is too deeply nested"}},
+		{"invalid document", `true`, `{`, []string{"$: invalid JSON: unexpected EOF"}},
+	}
+
+	for _, tc := range cases {
+		schema, err := Parse([]byte(tc.schema))
+		if !assert.NoError(t, err, tc.name) {
+			continue
+		}
+
+		var errs []string
+		for _, e := range schema.Validate([]byte(tc.doc)) {
+			errs = append(errs, e.Error())
+		}
+
+		assert.Equal(t, tc.errs, errs, tc.name)
+	}
+}
+
+func TestValidateValueFloats(t *testing.T) {
+	schema, err := Parse([]byte(`{"properties":{"n":{"type":"integer","maximum":10}}}`))
+	assert.NoError(t, err)
+
+	assert.Empty(t, schema.ValidateValue(map[string]interface{}{"n": float64(3)}))
+	assert.Equal(t, []ValidationError{{Path: "$.n", Message: "expected integer, got number"}}, schema.ValidateValue(map[string]interface{}{"n": 3.5}))
+	assert.Equal(t, []ValidationError{{Path: "$.n", Message: "11 is greater than the maximum 10"}}, schema.ValidateValue(map[string]interface{}{"n": float64(11)}))
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []string{
+		`{`,
+		`1`,
+		`"string"`,
+		`{"pattern":"("}`,
+		`{"properties":{"a":{"pattern":"[a-"}}}`,
+		`{"patternProperties":{"(":{}}}`,
+		`{} {}`,
+	}
+
+	for _, tc := range cases {
+		_, err := Parse([]byte(tc))
+		assert.Error(t, err, tc)
+	}
+
+	_, err := Parse([]byte(`{"enum":["("],"examples":["("]}`))
+	assert.NoError(t, err)
+}
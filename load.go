@@ -0,0 +1,524 @@
+package gopherman
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// LoadOptions configures a load run. See Tester.Load
+type LoadOptions struct {
+	// Items are the names of the items each worker sends, in order. If empty, each
+	// worker runs every item of every collection in collection order, as a flow
+	Items []string
+
+	// Concurrency is how many workers send requests at once, 1 if zero
+	Concurrency int
+
+	// Duration stops the run after this long, Requests after this many requests in total.
+	// At least one of them must be set
+	Duration time.Duration
+	Requests int
+
+	// Rate limits the requests per second sent by all workers together, unlimited if zero
+	Rate float64
+
+	// Thresholds fail the run when exceeded. See LoadReport.Check
+	Thresholds LoadThresholds
+}
+
+// LoadThresholds are the limits checked by LoadReport.Check. Zero values aren't checked, except ErrorRate
+type LoadThresholds struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+
+	// ErrorRate is the highest allowed fraction of failed requests, from 0 to 1. It isn't checked
+	// if nil, so a pointer to 0 allows no errors at all
+	ErrorRate *float64
+
+	// Throughput is the lowest allowed number of requests per second
+	Throughput float64
+}
+
+// LoadStats are the measurements for one item, or for the whole run
+type LoadStats struct {
+	Name       string
+	Requests   int
+	Errors     int
+	ErrorRate  float64
+	Throughput float64
+
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+
+	latencies []time.Duration
+}
+
+// LoadReport is the outcome of a load run
+type LoadReport struct {
+	Start    time.Time
+	Duration time.Duration
+
+	// Items are in the order they were first sent
+	Items []LoadStats
+	Total LoadStats
+
+	// Errors holds one example of each distinct error, keyed by item name
+	Errors map[string]string
+}
+
+// Load sends the selected items repeatedly, as configured by opts, and measures their latency.
+// Each worker starts with its own copy of t's runtime variables and its own cookie jar, seeded
+// with t.Cookies, so values extracted by one item feed the next item in the same worker's flow.
+// Requests aren't retried, and bodies aren't compared: a request fails if it can't be sent or its
+// status differs from its example's (or is 4xx or 5xx when it has no example). Requests that fail
+// before they're sent, such as those using undefined variables, count as errors but have no latency
+func (t *Tester) Load(ctx context.Context, opts LoadOptions) (*LoadReport, error) {
+	if opts.Duration <= 0 && opts.Requests <= 0 {
+		return nil, errors.New("load run needs a Duration or a number of Requests")
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	flow, err := t.loadFlow(opts.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.WaitReady(ctx); err != nil {
+		return nil, err
+	}
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	l := &loader{
+		tester:   t,
+		flow:     flow,
+		limit:    opts.Requests,
+		stats:    map[string]*LoadStats{},
+		errors:   map[string]string{},
+		interval: rateInterval(opts.Rate),
+		seed:     t.Vars.LocalCopy(),
+		scopes:   map[*postman.Collection]*postman.Scope{},
+	}
+
+	// resolved once, so that workers don't resolve secrets concurrently on every request
+	for _, step := range flow {
+		if _, ok := l.scopes[step.collection]; ok {
+			continue
+		}
+
+		scope, err := t.scopeFor(step.collection)
+		if err != nil {
+			return nil, err
+		}
+
+		l.scopes[step.collection] = scope
+	}
+
+	jars := make([]http.CookieJar, opts.Concurrency)
+	for i := range jars {
+		jar, err := newCookieJar(t.Cookies)
+		if err != nil {
+			return nil, err
+		}
+
+		jars[i] = jar
+	}
+
+	start := time.Now()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func(jar http.CookieJar) {
+			defer wg.Done()
+			l.work(ctx, jar)
+		}(jars[i])
+	}
+
+	wg.Wait()
+
+	return l.report(start, time.Since(start)), nil
+}
+
+// RunLoad runs Load, logs the report, and fails tst if it exceeds opts.Thresholds
+func (t *Tester) RunLoad(tst *testing.T, opts LoadOptions) *LoadReport {
+	tst.Helper()
+
+	report, err := t.Load(context.Background(), opts)
+	if err != nil {
+		tst.Fatal(err)
+	}
+
+	tst.Log("\n" + report.String())
+
+	AssertErrors(tst, report.Check(opts.Thresholds))
+
+	return report
+}
+
+// loadStep is one item in a load flow
+type loadStep struct {
+	collection *postman.Collection
+	name       string
+	itm        *postman.CollectionItem
+}
+
+// loadFlow returns the items each load worker sends, in order
+func (t *Tester) loadFlow(names []string) ([]loadStep, error) {
+	flow := []loadStep{}
+
+	for i := range t.Collections {
+		collection := &t.Collections[i]
+
+		if len(names) == 0 {
+			collection.Walk(func(folders []string, itm *postman.CollectionItem) {
+				name := strings.Join(append(append([]string{}, folders...), itm.Name), "/")
+				flow = append(flow, loadStep{collection: collection, name: name, itm: itm})
+			})
+
+			continue
+		}
+
+		for _, name := range names {
+			if itm := collection.ItemWithName(name); itm != nil {
+				flow = append(flow, loadStep{collection: collection, name: name, itm: itm})
+			}
+		}
+	}
+
+	if len(flow) == 0 {
+		return nil, errors.New("no items to load")
+	}
+
+	if len(t.Collections) > 1 {
+		for i := range flow {
+			flow[i].name = flow[i].collection.Info.Name + "/" + flow[i].name
+		}
+	}
+
+	return flow, nil
+}
+
+// loader holds the state shared by the workers of a load run
+type loader struct {
+	tester *Tester
+	flow   []loadStep
+
+	// seed holds the runtime variables each worker starts with, scopes the variables of each collection
+	seed   map[string]string
+	scopes map[*postman.Collection]*postman.Scope
+
+	// limit is the most requests to send, unlimited if zero
+	limit int
+	sent  int
+
+	// interval is the time between requests allowed by the rate limit, next the time of the next
+	interval time.Duration
+	next     time.Time
+
+	order  []string
+	stats  map[string]*LoadStats
+	errors map[string]string
+	mu     sync.Mutex
+}
+
+// work runs the flow repeatedly until ctx is done or the request limit is reached, keeping cookies in jar
+func (l *loader) work(ctx context.Context, jar http.CookieJar) {
+	local := map[string]string{}
+	for k, v := range l.seed {
+		local[k] = v
+	}
+
+	for {
+		for _, step := range l.flow {
+			if !l.claim(ctx) {
+				return
+			}
+
+			latency, sent, err := l.send(ctx, step, local, jar)
+			if err != nil && ctx.Err() != nil {
+				// requests cut off by the end of the run aren't counted
+				return
+			}
+
+			l.record(step.name, latency, sent, err)
+		}
+	}
+}
+
+// claim reserves the next request, waiting for the rate limit. It returns false when the run is over
+func (l *loader) claim(ctx context.Context) bool {
+	l.mu.Lock()
+
+	if l.limit > 0 && l.sent >= l.limit {
+		l.mu.Unlock()
+		return false
+	}
+
+	l.sent++
+
+	wait := time.Duration(0)
+	if l.interval > 0 {
+		now := time.Now()
+		if l.next.Before(now) {
+			l.next = now
+		}
+
+		wait = l.next.Sub(now)
+		l.next = l.next.Add(l.interval)
+	}
+
+	l.mu.Unlock()
+
+	if wait > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+	}
+
+	return ctx.Err() == nil
+}
+
+// send sends one step's request using the worker's runtime variables and cookie jar. It returns
+// the request's latency and whether it was sent, which it isn't if it can't be built
+func (l *loader) send(ctx context.Context, step loadStep, local map[string]string, jar http.CookieJar) (time.Duration, bool, error) {
+	t := l.tester
+
+	scope := l.scopes[step.collection].WithLocal(local)
+
+	httpReq, client, err := t.buildRequest(&step.itm.Request, scope)
+	if err != nil {
+		return 0, false, err
+	}
+
+	withJar := *client
+	withJar.Jar = jar
+
+	start := time.Now()
+
+	actual, header, err := t.attempt(ctx, &withJar, httpReq)
+	latency := time.Since(start)
+	if err != nil {
+		return latency, true, err
+	}
+
+	if err := extract(scope, t.extractionsFor(step.itm), actual, header); err != nil {
+		return latency, true, err
+	}
+
+	if len(step.itm.Response) > 0 && step.itm.Response[0].Status != 0 {
+		if actual.Status != step.itm.Response[0].Status {
+			return latency, true, fmt.Errorf("expected status %d, got %d", step.itm.Response[0].Status, actual.Status)
+		}
+	} else if actual.Status >= 400 {
+		return latency, true, fmt.Errorf("got status %d", actual.Status)
+	}
+
+	return latency, true, nil
+}
+
+// record counts a request, adding its latency to the stats only if it was sent
+func (l *loader) record(name string, latency time.Duration, sent bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats, ok := l.stats[name]
+	if !ok {
+		stats = &LoadStats{Name: name}
+		l.stats[name] = stats
+		l.order = append(l.order, name)
+	}
+
+	stats.Requests++
+
+	if sent {
+		stats.latencies = append(stats.latencies, latency)
+	}
+
+	if err != nil {
+		stats.Errors++
+
+		if _, ok := l.errors[name]; !ok {
+			l.errors[name] = err.Error()
+		}
+	}
+}
+
+func (l *loader) report(start time.Time, elapsed time.Duration) *LoadReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	report := &LoadReport{
+		Start:    start,
+		Duration: elapsed,
+		Total:    LoadStats{Name: "total"},
+		Errors:   l.errors,
+	}
+
+	for _, name := range l.order {
+		stats := l.stats[name]
+
+		report.Total.Requests += stats.Requests
+		report.Total.Errors += stats.Errors
+		report.Total.latencies = append(report.Total.latencies, stats.latencies...)
+
+		stats.summarize(elapsed)
+		report.Items = append(report.Items, *stats)
+	}
+
+	report.Total.summarize(elapsed)
+
+	return report
+}
+
+// summarize computes the stats' rates and percentiles from its latencies
+func (s *LoadStats) summarize(elapsed time.Duration) {
+	if s.Requests == 0 {
+		return
+	}
+
+	s.ErrorRate = float64(s.Errors) / float64(s.Requests)
+
+	if elapsed > 0 {
+		s.Throughput = float64(s.Requests) / elapsed.Seconds()
+	}
+
+	if len(s.latencies) == 0 {
+		return
+	}
+
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+
+	sum := time.Duration(0)
+	for _, l := range s.latencies {
+		sum += l
+	}
+
+	s.Min = s.latencies[0]
+	s.Max = s.latencies[len(s.latencies)-1]
+	s.Mean = sum / time.Duration(len(s.latencies))
+	s.P50 = percentile(s.latencies, 50)
+	s.P90 = percentile(s.latencies, 90)
+	s.P99 = percentile(s.latencies, 99)
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
+
+func rateInterval(rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// Check returns an error for each item, and the total, that exceeds thresholds
+func (r *LoadReport) Check(thresholds LoadThresholds) []error {
+	errs := []error{}
+
+	for _, stats := range append(append([]LoadStats{}, r.Items...), r.Total) {
+		errs = append(errs, stats.check(thresholds)...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (s *LoadStats) check(thresholds LoadThresholds) []error {
+	errs := []error{}
+
+	latencies := []struct {
+		name          string
+		actual, limit time.Duration
+	}{
+		{"p50", s.P50, thresholds.P50},
+		{"p90", s.P90, thresholds.P90},
+		{"p99", s.P99, thresholds.P99},
+	}
+
+	for _, l := range latencies {
+		if l.limit > 0 && l.actual > l.limit {
+			errs = append(errs, fmt.Errorf("%s: %s latency %s exceeds %s", s.Name, l.name, l.actual, l.limit))
+		}
+	}
+
+	if thresholds.ErrorRate != nil && s.ErrorRate > *thresholds.ErrorRate {
+		errs = append(errs, fmt.Errorf("%s: error rate %.2f%% exceeds %.2f%%", s.Name, s.ErrorRate*100, *thresholds.ErrorRate*100))
+	}
+
+	if thresholds.Throughput > 0 && s.Name == "total" && s.Throughput < thresholds.Throughput {
+		errs = append(errs, fmt.Errorf("%s: throughput %.1f req/s is below %.1f req/s", s.Name, s.Throughput, thresholds.Throughput))
+	}
+
+	return errs
+}
+
+// String formats the report as a table, followed by an example of each item's errors
+func (r *LoadReport) String() string {
+	buf := &bytes.Buffer{}
+
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "item\trequests\terrors\treq/s\tmin\tmean\tp50\tp90\tp99\tmax\t")
+
+	for _, stats := range append(append([]LoadStats{}, r.Items...), r.Total) {
+		fmt.Fprintf(w, "%s\t%d\t%.2f%%\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			stats.Name, stats.Requests, stats.ErrorRate*100, stats.Throughput,
+			roundLatency(stats.Min), roundLatency(stats.Mean), roundLatency(stats.P50),
+			roundLatency(stats.P90), roundLatency(stats.P99), roundLatency(stats.Max))
+	}
+
+	w.Flush()
+
+	for _, stats := range r.Items {
+		if err, ok := r.Errors[stats.Name]; ok {
+			fmt.Fprintf(buf, "%s: %s\n", stats.Name, err)
+		}
+	}
+
+	return buf.String()
+}
+
+func roundLatency(d time.Duration) time.Duration {
+	if d > time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,115 @@
+package gopherman
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+// sessionHandler logs in by setting a new session cookie, and only accepts /me with the session it was told about
+func sessionHandler() http.Handler {
+	mu := sync.Mutex{}
+	next := 0
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			mu.Lock()
+			next++
+			session := fmt.Sprint(next)
+			mu.Unlock()
+
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: session, Path: "/"})
+			fmt.Fprintf(w, `{"session":%q}`, session)
+		case "/me":
+			cookie, err := r.Cookie("session")
+			if err != nil || cookie.Value != r.Header.Get("X-Session") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	})
+}
+
+func sessionItems() []postman.CollectionItem {
+	return []postman.CollectionItem{
+		{
+			Name:      "login",
+			Request:   postman.Request{Method: http.MethodPost, URL: postman.URL{Raw: "http://localhost/login"}},
+			Gopherman: &postman.Annotation{Extract: []postman.Extraction{{Variable: "session", Path: "$.session"}}},
+		},
+		{
+			Name: "me",
+			Request: postman.Request{
+				Method: http.MethodGet,
+				Header: []postman.Header{{Key: "X-Session", Value: "{{session}}"}},
+				URL:    postman.URL{Raw: "http://localhost/me"},
+			},
+		},
+		{
+			Name:    "broken",
+			Request: postman.Request{Method: http.MethodGet, URL: postman.URL{Raw: "http://localhost/{{undefined}}"}},
+		},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	srv := httptest.NewServer(sessionHandler())
+	defer srv.Close()
+
+	dir, file := writeCollection(t, sessionItems()...)
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithServer(srv, dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := tester.Load(context.Background(), LoadOptions{Concurrency: 4, Requests: 120})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := map[string]LoadStats{}
+	for _, s := range report.Items {
+		stats[s.Name] = s
+	}
+
+	assert.Equal(t, 120, report.Total.Requests)
+	assert.True(t, stats["login"].Requests > 0)
+	assert.Equal(t, 0, stats["login"].Errors)
+	// each worker keeps its own session, so none is overwritten by another worker's login
+	assert.Equal(t, 0, stats["me"].Errors)
+	assert.True(t, stats["me"].Min > 0)
+
+	// requests that can't be built are errors without a latency
+	assert.True(t, stats["broken"].Requests > 0)
+	assert.Equal(t, stats["broken"].Requests, stats["broken"].Errors)
+	assert.Equal(t, float64(1), stats["broken"].ErrorRate)
+	assert.Zero(t, stats["broken"].Min)
+	assert.Zero(t, stats["broken"].P50)
+}
+
+func TestLoadErrorRateThreshold(t *testing.T) {
+	report := &LoadReport{Items: []LoadStats{{Name: "me", Requests: 10}, {Name: "login", Requests: 10, Errors: 1, ErrorRate: 0.1}}}
+
+	assert.Empty(t, report.Check(LoadThresholds{}))
+
+	none := 0.0
+	errs := report.Check(LoadThresholds{ErrorRate: &none})
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "login: error rate 10.00% exceeds 0.00%")
+	}
+
+	some := 0.1
+	assert.Empty(t, report.Check(LoadThresholds{ErrorRate: &some}))
+}
@@ -0,0 +1,335 @@
+package gopherman
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/cohix/gopherman/postman"
+)
+
+// Parts of a request that a mutation changes
+const (
+	mutatedBody   = "body"
+	mutatedQuery  = "query"
+	mutatedHeader = "header"
+)
+
+// mutant is a request with one mutation applied
+type mutant struct {
+	// desc describes the mutation, e.g. body $.user.name: huge string
+	desc string
+	part string
+	// name is the header, query parameter or top-level body key that was mutated,
+	// which minimizing must keep. It's empty when the whole body was replaced
+	name string
+	req  postman.Request
+}
+
+// hugeString is long enough to overflow fixed-size buffers and naive length checks
+var hugeString = strings.Repeat("A", 1<<16)
+
+// fuzzValue is a value substituted for a JSON value
+type fuzzValue struct {
+	desc  string
+	value interface{}
+}
+
+var jsonFuzzValues = []fuzzValue{
+	{"null", nil},
+	{"empty string", ""},
+	{"huge string", hugeString},
+	{"zero", 0},
+	{"negative number", -1},
+	{"max int32", math.MaxInt32},
+	{"max int32 + 1", int64(math.MaxInt32) + 1},
+	{"max int64", int64(math.MaxInt64)},
+	{"min int64", int64(math.MinInt64)},
+	{"huge number", 1e308},
+	{"fraction", 0.5},
+	{"boolean", true},
+	{"empty object", map[string]interface{}{}},
+	{"empty array", []interface{}{}},
+	{"special characters", "'\"<>%{}\\\x00"},
+	{"unicode", "ünïcødé ✓ 𝄞"},
+	{"format string", "%s%n%x"},
+	{"path traversal", "../../../../etc/passwd"},
+}
+
+// stringFuzzValues are substituted for query parameter and header values
+var stringFuzzValues = []fuzzValue{
+	{"empty string", ""},
+	{"huge string", hugeString},
+	{"zero", "0"},
+	{"negative number", "-1"},
+	{"max int64 + 1", "9223372036854775808"},
+	{"huge number", "1e308"},
+	{"not a number", "NaN"},
+	{"null", "null"},
+	{"special characters", "'\"<>%{}\\"},
+	{"unicode", "ünïcødé ✓ 𝄞"},
+	{"format string", "%s%n%x"},
+	{"path traversal", "../../../../etc/passwd"},
+}
+
+// mutants returns a copy of req for each mutation of its JSON body, query parameters and headers
+func mutants(req *postman.Request) []mutant {
+	muts := bodyMutants(req)
+	muts = append(muts, queryMutants(req)...)
+	muts = append(muts, headerMutants(req)...)
+
+	return muts
+}
+
+// copyRequest copies req deeply enough to mutate the copy
+func copyRequest(req *postman.Request) postman.Request {
+	cp := *req
+	cp.Header = append([]postman.Header{}, req.Header...)
+
+	return cp
+}
+
+func bodyMutants(req *postman.Request) []mutant {
+	raw := req.Body.Raw
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	muts := []mutant{}
+	whole := func(desc, body string) {
+		m := mutant{desc: "body: " + desc, part: mutatedBody, req: copyRequest(req)}
+		m.req.Body.Raw = body
+		muts = append(muts, m)
+	}
+
+	whole("empty", "")
+
+	root, err := parseOrderedJSON([]byte(raw))
+	if err != nil {
+		// not JSON, so only its size can be mutated
+		whole("huge", hugeString)
+		return muts
+	}
+
+	whole("truncated JSON", raw[:len(raw)/2])
+	whole("null", "null")
+
+	if root.isObject {
+		whole("array instead of object", "[]")
+	} else {
+		whole("object instead of "+jsonNodeType(root), "{}")
+	}
+
+	nodePaths(root, nil, func(path []pathSegment) {
+		name := ""
+		if path[0].kind == keySegment {
+			name = path[0].key
+		}
+
+		add := func(desc string, val *jsonNode) {
+			body, ok := replaceNode(raw, path, val)
+			if !ok {
+				return
+			}
+
+			m := mutant{desc: "body " + formatPath(path) + ": " + desc, part: mutatedBody, name: name, req: copyRequest(req)}
+			m.req.Body.Raw = body
+			muts = append(muts, m)
+		}
+
+		add("missing", nil)
+
+		for _, v := range jsonFuzzValues {
+			add(v.desc, newValueNode(v.value))
+		}
+	})
+
+	return muts
+}
+
+// nodePaths calls fn with the path of every value below n. Only the first item of each array is visited
+func nodePaths(n *jsonNode, path []pathSegment, fn func([]pathSegment)) {
+	for i, k := range n.keys {
+		p := append(append([]pathSegment{}, path...), keySeg(k))
+		fn(p)
+		nodePaths(n.values[i], p, fn)
+	}
+
+	if len(n.items) > 0 {
+		p := append(append([]pathSegment{}, path...), indexSeg(0))
+		fn(p)
+		nodePaths(n.items[0], p, fn)
+	}
+}
+
+// replaceNode replaces the value at path in a JSON document, or removes it if val is nil
+func replaceNode(raw string, path []pathSegment, val *jsonNode) (string, bool) {
+	root, err := parseOrderedJSON([]byte(raw))
+	if err != nil {
+		return "", false
+	}
+
+	parent := root
+	for _, seg := range path[:len(path)-1] {
+		switch {
+		case seg.kind == keySegment && parent.isObject:
+			i := exactKey(parent, seg.key)
+			if i < 0 {
+				return "", false
+			}
+
+			parent = parent.values[i]
+		case seg.kind == indexSegment && parent.isArray && seg.index < len(parent.items):
+			parent = parent.items[seg.index]
+		default:
+			return "", false
+		}
+	}
+
+	last := path[len(path)-1]
+	switch {
+	case last.kind == keySegment && parent.isObject:
+		i := exactKey(parent, last.key)
+		if i < 0 {
+			return "", false
+		}
+
+		if val == nil {
+			parent.remove(i)
+		} else {
+			parent.values[i] = val
+		}
+	case last.kind == indexSegment && parent.isArray && last.index < len(parent.items):
+		if val == nil {
+			parent.remove(last.index)
+		} else {
+			parent.items[last.index] = val
+		}
+	default:
+		return "", false
+	}
+
+	return compactNode(root), true
+}
+
+// exactKey returns the index of key in an object, matching case-sensitively
+func exactKey(n *jsonNode, key string) int {
+	for i, k := range n.keys {
+		if k == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func compactNode(n *jsonNode) string {
+	buf := &bytes.Buffer{}
+	n.write(buf, "", 0)
+
+	compact := &bytes.Buffer{}
+	if err := json.Compact(compact, buf.Bytes()); err != nil {
+		return buf.String()
+	}
+
+	return compact.String()
+}
+
+func jsonNodeType(n *jsonNode) string {
+	switch {
+	case n.isObject:
+		return "object"
+	case n.isArray:
+		return "array"
+	}
+
+	var v interface{}
+	json.Unmarshal(n.raw, &v)
+
+	return jsonType(v)
+}
+
+// splitQuery splits a raw URL into the part before the query and the query's parameters,
+// without parsing it, so that variable references are kept as they are
+func splitQuery(raw string) (string, []string) {
+	i := strings.Index(raw, "?")
+	if i < 0 {
+		return raw, nil
+	}
+
+	params := []string{}
+	for _, p := range strings.Split(raw[i+1:], "&") {
+		if p != "" {
+			params = append(params, p)
+		}
+	}
+
+	return raw[:i], params
+}
+
+func joinQuery(base string, params []string) string {
+	if len(params) == 0 {
+		return base
+	}
+
+	return base + "?" + strings.Join(params, "&")
+}
+
+func paramName(param string) string {
+	return strings.SplitN(param, "=", 2)[0]
+}
+
+func queryMutants(req *postman.Request) []mutant {
+	base, params := splitQuery(req.URL.Raw)
+	muts := []mutant{}
+
+	for i, p := range params {
+		name := paramName(p)
+
+		add := func(desc string, mutated []string) {
+			m := mutant{desc: "query " + name + ": " + desc, part: mutatedQuery, name: name, req: copyRequest(req)}
+			m.req.URL.Raw = joinQuery(base, mutated)
+			muts = append(muts, m)
+		}
+
+		add("missing", withoutString(params, i))
+
+		for _, v := range stringFuzzValues {
+			mutated := append([]string{}, params...)
+			mutated[i] = name + "=" + url.QueryEscape(v.value.(string))
+			add(v.desc, mutated)
+		}
+
+		add("repeated", append(append([]string{}, params...), p))
+	}
+
+	return muts
+}
+
+func headerMutants(req *postman.Request) []mutant {
+	muts := []mutant{}
+
+	for i, h := range req.Header {
+		add := func(desc string, mutated postman.Request) {
+			muts = append(muts, mutant{desc: "header " + h.Key + ": " + desc, part: mutatedHeader, name: h.Key, req: mutated})
+		}
+
+		missing := copyRequest(req)
+		missing.Header = append(missing.Header[:i], missing.Header[i+1:]...)
+		add("missing", missing)
+
+		for _, v := range stringFuzzValues {
+			mutated := copyRequest(req)
+			mutated.Header[i].Value = v.value.(string)
+			add(v.desc, mutated)
+		}
+	}
+
+	return muts
+}
+
+func withoutString(list []string, i int) []string {
+	return append(append([]string{}, list[:i]...), list[i+1:]...)
+}
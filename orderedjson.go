@@ -0,0 +1,332 @@
+package gopherman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonNode is a JSON value that keeps its object keys in their original order,
+// so that a file can be edited and written back without reshuffling it
+type jsonNode struct {
+	// keys and values are set for objects, items for arrays, and raw for everything else
+	keys   []string
+	values []*jsonNode
+	items  []*jsonNode
+	raw    json.RawMessage
+
+	isObject bool
+	isArray  bool
+
+	// src is the document a parsed object or array came from, with the node at src[start:end]
+	// and each of its values or items at the spans in slots, so that the parts of it that
+	// haven't changed are written back byte for byte
+	src   []byte
+	start int
+	end   int
+	slots [][2]int
+
+	// reshaped is set when keys or items are added or removed, so the node is rendered afresh
+	reshaped bool
+}
+
+// nodeDecoder decodes a document into jsonNodes, keeping track of where each node is in it
+type nodeDecoder struct {
+	dec  *json.Decoder
+	data []byte
+}
+
+func parseOrderedJSON(data []byte) (*jsonNode, error) {
+	d := &nodeDecoder{dec: json.NewDecoder(bytes.NewReader(data)), data: data}
+	d.dec.UseNumber()
+
+	node, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := d.dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+
+	return node, nil
+}
+
+// next returns the offset of the next value, skipping whitespace and the separators the decoder hasn't returned
+func (d *nodeDecoder) next() int {
+	off := int(d.dec.InputOffset())
+	for off < len(d.data) && strings.IndexByte(" \t\r\n,:", d.data[off]) >= 0 {
+		off++
+	}
+
+	return off
+}
+
+func (d *nodeDecoder) decode() (*jsonNode, error) {
+	start := d.next()
+
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		// scalars are kept exactly as written, so escapes and number formats survive
+		return &jsonNode{raw: json.RawMessage(d.data[start:d.dec.InputOffset()])}, nil
+	}
+
+	node := &jsonNode{src: d.data, start: start}
+
+	switch delim {
+	case '{':
+		node.isObject = true
+	case '[':
+		node.isArray = true
+		node.items = []*jsonNode{}
+	default:
+		return nil, fmt.Errorf("unexpected %s", delim)
+	}
+
+	for d.dec.More() {
+		if node.isObject {
+			keyTok, err := d.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			node.keys = append(node.keys, keyTok.(string))
+		}
+
+		valStart := d.next()
+
+		val, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		node.slots = append(node.slots, [2]int{valStart, int(d.dec.InputOffset())})
+
+		if node.isObject {
+			node.values = append(node.values, val)
+		} else {
+			node.items = append(node.items, val)
+		}
+	}
+
+	if _, err := d.dec.Token(); err != nil {
+		return nil, err
+	}
+
+	node.end = int(d.dec.InputOffset())
+
+	return node, nil
+}
+
+// newValueNode returns a node holding val, rendered in the style of the document it's written into
+func newValueNode(val interface{}) *jsonNode {
+	raw, err := marshalNoEscape(val)
+	if err != nil {
+		raw = json.RawMessage("null")
+	}
+
+	node, err := parseOrderedJSON(raw)
+	if err != nil {
+		return &jsonNode{raw: json.RawMessage("null")}
+	}
+
+	node.detach()
+
+	return node
+}
+
+// detach forgets where the node and its children came from, so they're rendered afresh
+func (n *jsonNode) detach() {
+	n.src = nil
+	n.slots = nil
+
+	for _, v := range n.values {
+		v.detach()
+	}
+
+	for _, itm := range n.items {
+		itm.detach()
+	}
+}
+
+// key returns the index of key in an object, matching case-insensitively like encoding/json does
+func (n *jsonNode) key(key string) int {
+	for i, k := range n.keys {
+		if k == key {
+			return i
+		}
+	}
+
+	for i, k := range n.keys {
+		if strings.EqualFold(k, key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// has returns true if an object has key, spelled exactly as given
+func (n *jsonNode) has(key string) bool {
+	for _, k := range n.keys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get returns the value for key in an object, or nil
+func (n *jsonNode) get(key string) *jsonNode {
+	if i := n.key(key); i >= 0 {
+		return n.values[i]
+	}
+
+	return nil
+}
+
+// set replaces the value for key in an object, keeping its position and spelling,
+// or adds it at the end if it isn't there
+func (n *jsonNode) set(key string, val *jsonNode) {
+	if i := n.key(key); i >= 0 {
+		n.values[i] = val
+		return
+	}
+
+	n.keys = append(n.keys, key)
+	n.values = append(n.values, val)
+	n.reshaped = true
+}
+
+// remove removes the i'th key of an object or item of an array
+func (n *jsonNode) remove(i int) {
+	if n.isObject {
+		n.keys = append(n.keys[:i], n.keys[i+1:]...)
+		n.values = append(n.values[:i], n.values[i+1:]...)
+	} else {
+		n.items = append(n.items[:i], n.items[i+1:]...)
+	}
+
+	n.reshaped = true
+}
+
+// add appends an item to an array
+func (n *jsonNode) add(itm *jsonNode) {
+	n.items = append(n.items, itm)
+	n.reshaped = true
+}
+
+// write renders the node with indent, in the style of json.MarshalIndent. Parsed objects and
+// arrays that haven't been reshaped are written as they were, with only changed values rendered
+func (n *jsonNode) write(buf *bytes.Buffer, indent string, depth int) {
+	if n.src != nil && !n.reshaped {
+		children := n.values
+		if n.isArray {
+			children = n.items
+		}
+
+		pos := n.start
+		for i, child := range children {
+			buf.Write(n.src[pos:n.slots[i][0]])
+			child.write(buf, indent, depth+1)
+			pos = n.slots[i][1]
+		}
+
+		buf.Write(n.src[pos:n.end])
+
+		return
+	}
+
+	switch {
+	case n.isObject:
+		if len(n.keys) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+
+		buf.WriteString("{\n")
+
+		for i, k := range n.keys {
+			buf.WriteString(strings.Repeat(indent, depth+1))
+
+			key, _ := marshalNoEscape(k)
+			buf.Write(key)
+			buf.WriteString(": ")
+
+			n.values[i].write(buf, indent, depth+1)
+
+			if i < len(n.keys)-1 {
+				buf.WriteString(",")
+			}
+
+			buf.WriteString("\n")
+		}
+
+		buf.WriteString(strings.Repeat(indent, depth) + "}")
+
+	case n.isArray:
+		if len(n.items) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+
+		buf.WriteString("[\n")
+
+		for i, itm := range n.items {
+			buf.WriteString(strings.Repeat(indent, depth+1))
+			itm.write(buf, indent, depth+1)
+
+			if i < len(n.items)-1 {
+				buf.WriteString(",")
+			}
+
+			buf.WriteString("\n")
+		}
+
+		buf.WriteString(strings.Repeat(indent, depth) + "]")
+
+	default:
+		buf.Write(n.raw)
+	}
+}
+
+// detectIndent returns the indentation used by a pretty-printed JSON file, defaulting to a tab
+func detectIndent(data []byte) string {
+	lines := strings.Split(string(data), "\n")
+	if first := strings.TrimSpace(lines[0]); first != "{" && first != "[" {
+		return "\t"
+	}
+
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+
+	return "\t"
+}
+
+// marshalNoEscape marshals val without escaping <, > and &, which are common in bodies and URLs
+func marshalNoEscape(val interface{}) (json.RawMessage, error) {
+	buf := &bytes.Buffer{}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(val); err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
@@ -0,0 +1,89 @@
+package gopherman
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedJSONRoundTrip(t *testing.T) {
+	docs := []string{
+		`{"b":1,"a":[1,2,{"c":null}]}`,
+		"{\n\t\"b\": 1,\n\t\"a\": [\n\t\t1\n\t]\n}",
+		"{\n  \"name\": \"caf\u00e9 \\u00e9\",\n  \"url\": \"a\\u0026b <c>\",\n  \"n\": 1.50e3\n}",
+		`[ {"x" : "y"} , [ ] , { } ]`,
+		`"just a string"`,
+	}
+
+	for _, doc := range docs {
+		root, err := parseOrderedJSON([]byte(doc))
+		if !assert.NoError(t, err, doc) {
+			continue
+		}
+
+		buf := &bytes.Buffer{}
+		root.write(buf, "\t", 0)
+		assert.Equal(t, doc, buf.String())
+	}
+}
+
+func TestOrderedJSONEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		edit     func(root *jsonNode)
+		expected string
+	}{
+		{
+			"replace a value in a minified document",
+			`{"name":"café","response":[{"code":200,"body":"old"}]}`,
+			func(root *jsonNode) { root.get("response").items[0].set("body", newValueNode("new & <improved>")) },
+			`{"name":"café","response":[{"code":200,"body":"new & <improved>"}]}`,
+		},
+		{
+			"replace a value in a pretty document",
+			"{\n  \"a\": \"\\u00e9\",\n  \"b\": 1\n}",
+			func(root *jsonNode) { root.set("b", newValueNode(2)) },
+			"{\n  \"a\": \"\\u00e9\",\n  \"b\": 2\n}",
+		},
+		{
+			"add a key",
+			"{\n  \"a\": {\"kept\":true}\n}",
+			func(root *jsonNode) { root.set("b", newValueNode("x")) },
+			"{\n  \"a\": {\"kept\":true},\n  \"b\": \"x\"\n}",
+		},
+		{
+			"remove an item",
+			`[1, 2, 3]`,
+			func(root *jsonNode) { root.remove(1) },
+			"[\n  1,\n  3\n]",
+		},
+		{
+			"add an item",
+			`{"items":[]}`,
+			func(root *jsonNode) { root.get("items").add(newValueNode(map[string]int{"a": 1})) },
+			"{\"items\":[\n    {\n      \"a\": 1\n    }\n  ]}",
+		},
+	}
+
+	for _, test := range tests {
+		root, err := parseOrderedJSON([]byte(test.doc))
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		test.edit(root)
+
+		buf := &bytes.Buffer{}
+		root.write(buf, "  ", 0)
+		assert.Equal(t, test.expected, buf.String(), test.name)
+	}
+}
+
+func TestParseOrderedJSONErrors(t *testing.T) {
+	for _, doc := range []string{``, `{`, `{"a":}`, `[1,]`, `{} {}`} {
+		_, err := parseOrderedJSON([]byte(doc))
+		assert.Error(t, err, doc)
+	}
+}
@@ -0,0 +1,91 @@
+package postman
+
+import "encoding/json"
+
+// Annotation holds gopherman-specific settings for an item. It is stored in the
+// collection under the item's "gopherman" key, which Postman itself ignores
+type Annotation struct {
+	Extract []Extraction `json:"extract,omitempty"`
+
+	// Independent marks an item that neither uses nor produces chained values,
+	// so it can run in parallel with other independent items
+	Independent bool `json:"independent,omitempty"`
+
+	// Retry resends the item's request when it fails in a way that may be transient
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// Schema is a JSON Schema that the item's response bodies must conform to
+	Schema json.RawMessage `json:"schema,omitempty"`
+
+	// SchemaFile is a JSON Schema file, relative to the collection file, used if Schema isn't set
+	SchemaFile string `json:"schemaFile,omitempty"`
+
+	// Headers are checked against the headers of every response to the item
+	Headers []HeaderAssertion `json:"headers,omitempty"`
+}
+
+// HeaderAssertion checks a response header. With no Value, Pattern or Absent,
+// the header only has to be present
+type HeaderAssertion struct {
+	Name string `json:"name"`
+	// Value is the header's exact expected value
+	Value string `json:"value,omitempty"`
+	// Pattern is a regular expression the header's value must match
+	Pattern string `json:"pattern,omitempty"`
+	// Absent checks that the header is not present
+	Absent bool `json:"absent,omitempty"`
+}
+
+// RetryPolicy describes when and how often to resend a request
+type RetryPolicy struct {
+	// Retries is how many times to resend the request after the first attempt
+	Retries int `json:"retries"`
+	// Statuses are the response status codes to retry on, e.g. 503
+	Statuses []int `json:"statuses,omitempty"`
+	// ConnectionErrors retries when the request couldn't be sent or no response was received
+	ConnectionErrors bool `json:"connectionErrors,omitempty"`
+	// BackoffMS is the wait before the first retry in milliseconds, doubling after each retry
+	BackoffMS int `json:"backoffMs,omitempty"`
+}
+
+// RetriesStatus returns true if the policy retries responses with status
+func (r *RetryPolicy) RetriesStatus(status int) bool {
+	for _, s := range r.Statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Extraction sources
+const (
+	FromBody   = "body"
+	FromHeader = "header"
+	FromCookie = "cookie"
+)
+
+// Extraction copies a value from a response into a runtime variable, so that later requests can use it
+type Extraction struct {
+	// Variable is the name of the runtime variable to set
+	Variable string `json:"variable"`
+	// From is where to find the value: body (the default), header or cookie
+	From string `json:"from,omitempty"`
+	// Path is a JSON path such as $.data.id for body, or the header or cookie name
+	Path string `json:"path"`
+}
+
+// Extractions returns the item's annotated extractions, if any
+func (c *CollectionItem) Extractions() []Extraction {
+	if c.Gopherman == nil {
+		return nil
+	}
+
+	return c.Gopherman.Extract
+}
+
+// HasSchema returns true if the item is annotated with a JSON Schema, inline or in a file
+func (c *CollectionItem) HasSchema() bool {
+	return c.Gopherman != nil && (len(c.Gopherman.Schema) > 0 || c.Gopherman.SchemaFile != "")
+}
@@ -5,16 +5,19 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 // Collection represents a collection of requests
 type Collection struct {
-	Info CollectionInfo
-	Item []CollectionItem
-	Auth CollectionAuth
+	Info     CollectionInfo
+	Item     []CollectionItem
+	Auth     CollectionAuth
+	Variable []Variable `json:"Variable,omitempty"`
 }
 
 // CollectionInfo represents info about a collection
@@ -39,9 +42,11 @@ type CollectionBearerAuth struct {
 
 // CollectionItem represents a request/response in a collection
 type CollectionItem struct {
-	Name     string
-	Request  Request
-	Response []Response
+	Name      string
+	Item      []CollectionItem `json:"Item,omitempty"`
+	Request   Request
+	Response  []Response
+	Gopherman *Annotation `json:"gopherman,omitempty"`
 }
 
 // Request represents a request to the endpoint
@@ -68,9 +73,53 @@ type Body struct {
 
 // Response describes a response
 type Response struct {
-	Mode   string
-	Raw    string
-	Status int
+	Name            string   `json:"Name,omitempty"`
+	OriginalRequest *Request `json:"originalRequest,omitempty"`
+	Mode            string
+	Raw             string
+	Status          int
+	StatusText      string   `json:"StatusText,omitempty"`
+	Header          []Header `json:"Header,omitempty"`
+	Cookie          []Cookie `json:"Cookie,omitempty"`
+	// ResponseTime is how long the response took in milliseconds
+	ResponseTime int64 `json:"ResponseTime,omitempty"`
+}
+
+// UnmarshalJSON reads gopherman's own example format as well as Postman's, in which
+// the status code is "code", "status" is the status text and the body is "body"
+func (r *Response) UnmarshalJSON(data []byte) error {
+	type response Response
+
+	aux := struct {
+		response
+		Status json.RawMessage
+		Code   int
+		Body   *string
+	}{}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	*r = Response(aux.response)
+
+	if len(aux.Status) > 0 && string(aux.Status) != "null" {
+		if err := json.Unmarshal(aux.Status, &r.Status); err != nil {
+			if err := json.Unmarshal(aux.Status, &r.StatusText); err != nil {
+				return errors.Wrap(err, "status must be a number or a string")
+			}
+		}
+	}
+
+	if aux.Code != 0 {
+		r.Status = aux.Code
+	}
+
+	if aux.Body != nil && r.Raw == "" {
+		r.Raw = *aux.Body
+	}
+
+	return nil
 }
 
 // URL represents a URL
@@ -100,17 +149,99 @@ func NewCollection(name string, items []CollectionItem, auth *CollectionAuth) *C
 	return &collection
 }
 
-// ItemWithName gets an item with a particular name
+// CollectionFromFile loads a collection from a file
+func CollectionFromFile(filepath string) (*Collection, error) {
+	file, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := Collection{}
+	if err := json.Unmarshal(file, &collection); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse collection %s", filepath)
+	}
+
+	return &collection, nil
+}
+
+// ItemWithName gets a request item with a particular name, searching folders depth first
 func (c *Collection) ItemWithName(name string) *CollectionItem {
-	for i, itm := range c.Item {
+	return itemWithName(c.Item, name)
+}
+
+func itemWithName(items []CollectionItem, name string) *CollectionItem {
+	for i, itm := range items {
+		if itm.IsFolder() {
+			if found := itemWithName(items[i].Item, name); found != nil {
+				return found
+			}
+
+			continue
+		}
+
 		if itm.Name == name {
-			return &c.Item[i]
+			return &items[i]
 		}
 	}
 
 	return nil
 }
 
+// Walk calls fn for every request item in the collection, in collection order,
+// with the names of the folders containing it
+func (c *Collection) Walk(fn func(folders []string, itm *CollectionItem)) {
+	walk(nil, c.Item, fn)
+}
+
+func walk(folders []string, items []CollectionItem, fn func(folders []string, itm *CollectionItem)) {
+	for i := range items {
+		if items[i].IsFolder() {
+			walk(append(append([]string{}, folders...), items[i].Name), items[i].Item, fn)
+			continue
+		}
+
+		fn(folders, &items[i])
+	}
+}
+
+// VariableMap returns a map[string]string of the collection's variables
+func (c *Collection) VariableMap() map[string]string {
+	varMap := make(map[string]string)
+
+	for _, v := range c.Variable {
+		if !v.Disabled {
+			varMap[v.Key] = v.Value
+		}
+	}
+
+	return varMap
+}
+
+// ResolvedVariableMap is like VariableMap, but resolves secret variables through secrets
+func (c *Collection) ResolvedVariableMap(secrets *Secrets) (map[string]string, error) {
+	varMap := make(map[string]string)
+
+	for _, v := range c.Variable {
+		if v.Disabled {
+			continue
+		}
+
+		val, err := secrets.Resolve(v)
+		if err != nil {
+			return nil, err
+		}
+
+		varMap[v.Key] = val
+	}
+
+	return varMap, nil
+}
+
+// IsFolder returns true if the item is a folder of other items rather than a request
+func (c *CollectionItem) IsFolder() bool {
+	return c.Item != nil
+}
+
 // RequestFromHTTP converts an http request to a postman request
 func RequestFromHTTP(r *http.Request) (*Request, error) {
 	req := Request{
@@ -165,40 +296,149 @@ func RequestFromHTTP(r *http.Request) (*Request, error) {
 	return &req, nil
 }
 
-// ToHTTPRequest converts a postman request to an http request
+// ToHTTPRequest converts a postman request to an http request, or returns nil if it can't. See HTTPRequest
 func (r *Request) ToHTTPRequest(vars map[string]string) *http.Request {
-	tmplAddr, err := SubstVars(r.URL.Raw, vars)
+	req, err := r.HTTPRequest(vars)
 	if err != nil {
-		tmplAddr = r.URL.Raw
+		return nil
 	}
 
-	req, err := http.NewRequest(r.Method, tmplAddr, bytes.NewBuffer([]byte(r.Body.Raw)))
+	return req
+}
+
+// ToHTTPRequestWithScope converts a postman request to an http request, resolving variables through scope,
+// or returns nil if it can't. See HTTPRequestWithScope
+func (r *Request) ToHTTPRequestWithScope(scope *Scope) *http.Request {
+	req, err := r.HTTPRequestWithScope(scope)
 	if err != nil {
 		return nil
 	}
 
-	if vars != nil {
-		for _, h := range r.Header {
-			tmplKey, err := SubstVars(h.Key, vars)
-			if err != nil {
-				tmplKey = h.Key
-			}
+	return req
+}
 
-			tmplVal, err := SubstVars(h.Value, vars)
-			if err != nil {
-				tmplVal = h.Value
-			}
+// HTTPRequest converts a postman request to an http request, substituting vars into its URL, body and headers.
+// Text that isn't a valid template is sent as it is, but a variable missing from vars is an error.
+// With nil vars nothing is substituted
+func (r *Request) HTTPRequest(vars map[string]string) (*http.Request, error) {
+	subst := func(text string) (string, error) {
+		if vars == nil {
+			return text, nil
+		}
 
-			req.Header.Add(tmplKey, tmplVal)
+		out, err := SubstVars(text, vars)
+		if undefined, ok := err.(*UndefinedVariableError); ok {
+			return "", undefined
+		} else if err != nil {
+			return text, nil
 		}
 
-	} else {
-		for _, h := range r.Header {
-			req.Header.Add(h.Key, h.Value)
+		return out, nil
+	}
+
+	addr, err := subst(r.URL.Raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to substitute variables in URL")
+	}
+
+	body, err := subst(r.Body.Raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to substitute variables in body")
+	}
+
+	req, err := http.NewRequest(r.Method, addr, bytes.NewBuffer([]byte(body)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to NewRequest")
+	}
+
+	for _, h := range r.Header {
+		key, err := subst(h.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to substitute variables in header %s", h.Key)
 		}
+
+		val, err := subst(h.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to substitute variables in header %s", h.Key)
+		}
+
+		req.Header.Add(key, val)
 	}
 
-	return req
+	return req, nil
+}
+
+// HTTPRequestWithScope converts a postman request to an http request, resolving variables through scope
+func (r *Request) HTTPRequestWithScope(scope *Scope) (*http.Request, error) {
+	if scope == nil {
+		return r.HTTPRequest(nil)
+	}
+
+	return r.HTTPRequest(scope.Map())
+}
+
+// HTTPHeader returns the response's headers as an http.Header
+func (r *Response) HTTPHeader() http.Header {
+	header := http.Header{}
+
+	for _, h := range r.Header {
+		key := h.Key
+		if key == "" {
+			key = h.Name
+		}
+
+		header.Add(key, h.Value)
+	}
+
+	return header
+}
+
+// HeadersFromHTTP converts an http.Header to headers, sorted by key
+func HeadersFromHTTP(header http.Header) []Header {
+	keys := []string{}
+	for k := range header {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	headers := []Header{}
+	for _, k := range keys {
+		for _, v := range header[k] {
+			headers = append(headers, Header{Key: k, Value: v, Type: "text"})
+		}
+	}
+
+	return headers
+}
+
+// CookieFromHTTP converts an http.Cookie set by a response from host to a cookie
+func CookieFromHTTP(c *http.Cookie, host string) Cookie {
+	cookie := Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HTTPOnly: c.HttpOnly,
+	}
+
+	if !c.Expires.IsZero() {
+		expires := c.Expires
+		cookie.Expires = &expires
+	}
+
+	if cookie.Domain == "" {
+		cookie.Domain = host
+		cookie.HostOnly = true
+	}
+
+	if c.MaxAge > 0 {
+		expires := time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+		cookie.Expires = &expires
+	}
+
+	return cookie
 }
 
 // ToInterface unmarshals a response into an interface
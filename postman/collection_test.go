@@ -0,0 +1,108 @@
+package postman
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPRequest(t *testing.T) {
+	req := &Request{
+		Method: "POST",
+		URL:    URL{Raw: "http://{{host}}/users/{{id}}"},
+		Header: []Header{{Key: "Authorization", Value: "Bearer {{token}}"}},
+		Body:   Body{Mode: "raw", Raw: `{"id":"{{id}}"}`},
+	}
+
+	httpReq, err := req.HTTPRequest(map[string]string{"host": "api", "id": "7", "token": "t"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	body, _ := ioutil.ReadAll(httpReq.Body)
+
+	assert.Equal(t, "http://api/users/7", httpReq.URL.String())
+	assert.Equal(t, "Bearer t", httpReq.Header.Get("Authorization"))
+	assert.Equal(t, `{"id":"7"}`, string(body))
+
+	tests := []struct {
+		name string
+		vars map[string]string
+	}{
+		{"missing in URL", map[string]string{"id": "7", "token": "t"}},
+		{"missing in header", map[string]string{"host": "api", "id": "7"}},
+	}
+
+	for _, test := range tests {
+		_, err := req.HTTPRequest(test.vars)
+		assert.Error(t, err, test.name)
+		assert.Nil(t, req.ToHTTPRequest(test.vars), test.name)
+	}
+}
+
+func TestHTTPRequestLeavesInvalidTemplates(t *testing.T) {
+	req := &Request{Method: "POST", URL: URL{Raw: "http://api/{{id}}"}, Body: Body{Raw: `{"tmpl":"{{ unclosed"}`}}
+
+	httpReq, err := req.HTTPRequest(map[string]string{"id": "1"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	body, _ := ioutil.ReadAll(httpReq.Body)
+	assert.Equal(t, `{"tmpl":"{{ unclosed"}`, string(body))
+
+	// without vars nothing is substituted
+	httpReq, err = req.HTTPRequest(nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "http://api/%7B%7Bid%7D%7D", httpReq.URL.String())
+	}
+}
+
+func TestResponseUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		resp Response
+	}{
+		{"gopherman", `{"Name":"ok","Mode":"raw","Raw":"a","Status":201,"StatusText":"Created"}`, Response{Name: "ok", Mode: "raw", Raw: "a", Status: 201, StatusText: "Created"}},
+		{"postman", `{"name":"ok","status":"Created","code":201,"body":"a"}`, Response{Name: "ok", Raw: "a", Status: 201, StatusText: "Created"}},
+		{"postman with originalRequest", `{"name":"missing","code":404,"originalRequest":{"method":"GET","url":{"raw":"/users/2"}}}`, Response{Name: "missing", Status: 404, OriginalRequest: &Request{Method: "GET", URL: URL{Raw: "/users/2"}}}},
+	}
+
+	for _, test := range tests {
+		resp := Response{}
+		if assert.NoError(t, json.Unmarshal([]byte(test.json), &resp), test.name) {
+			assert.Equal(t, test.resp, resp, test.name)
+		}
+	}
+
+	assert.Error(t, json.Unmarshal([]byte(`{"status":true}`), &Response{}))
+}
+
+func TestToHTTPRequestWithoutVars(t *testing.T) {
+	req := &Request{
+		Method: "PUT",
+		URL:    URL{Raw: "http://api/users/7"},
+		Header: []Header{{Key: "Authorization", Value: "Bearer {{token}}"}},
+		Body:   Body{Mode: "raw", Raw: `{"name":"{{name}}"}`},
+	}
+
+	// nil vars skip substitution, so references are sent as they are rather than failing as undefined
+	httpReq := req.ToHTTPRequest(nil)
+	if !assert.NotNil(t, httpReq) {
+		return
+	}
+
+	body, _ := ioutil.ReadAll(httpReq.Body)
+
+	assert.Equal(t, "PUT", httpReq.Method)
+	assert.Equal(t, "http://api/users/7", httpReq.URL.String())
+	assert.Equal(t, "Bearer {{token}}", httpReq.Header.Get("Authorization"))
+	assert.Equal(t, `{"name":"{{name}}"}`, string(body))
+
+	// an empty map substitutes, so the same references are undefined
+	assert.Nil(t, req.ToHTTPRequest(map[string]string{}))
+	assert.NotNil(t, req.ToHTTPRequestWithScope(nil))
+}
@@ -0,0 +1,144 @@
+package postman
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cookie is a cookie as exported by Postman, or by newman's --export-cookie-jar
+type Cookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain"`
+	Path   string `json:"path,omitempty"`
+	// Expires is nil for a session cookie
+	Expires  *time.Time `json:"expires,omitempty"`
+	HostOnly bool       `json:"hostOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+}
+
+// UnmarshalJSON reads both Postman's cookies, which have a name, and newman's, which have a key.
+// Expiry may be a date, a Unix time in seconds, or "Infinity"
+func (c *Cookie) UnmarshalJSON(data []byte) error {
+	type cookie Cookie
+
+	aux := struct {
+		cookie
+		Key     string          `json:"key"`
+		Expires json.RawMessage `json:"expires"`
+	}{}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	*c = Cookie(aux.cookie)
+
+	if c.Name == "" {
+		c.Name = aux.Key
+	}
+
+	if len(aux.Expires) == 0 || string(aux.Expires) == "null" {
+		return nil
+	}
+
+	var seconds float64
+	if err := json.Unmarshal(aux.Expires, &seconds); err == nil {
+		expires := time.Unix(int64(seconds), 0)
+		c.Expires = &expires
+		return nil
+	}
+
+	var date string
+	if err := json.Unmarshal(aux.Expires, &date); err != nil {
+		return errors.Wrap(err, "invalid cookie expiry")
+	}
+
+	if date == "" || date == "Infinity" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, time.RFC1123, "Mon, 02-Jan-2006 15:04:05 MST"} {
+		if expires, err := time.Parse(layout, date); err == nil {
+			c.Expires = &expires
+			return nil
+		}
+	}
+
+	return errors.Errorf("invalid cookie expiry %s", date)
+}
+
+// CookiesFromFile reads cookies from a file holding either a list of cookies, or
+// an object with a cookies list, like newman's exported cookie jar
+func CookiesFromFile(filepath string) ([]Cookie, error) {
+	file, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	cookies := []Cookie{}
+
+	if bytes.HasPrefix(bytes.TrimSpace(file), []byte("[")) {
+		err = json.Unmarshal(file, &cookies)
+	} else {
+		jar := struct {
+			Cookies *[]Cookie `json:"cookies"`
+		}{Cookies: &cookies}
+
+		err = json.Unmarshal(file, &jar)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse cookies from %s", filepath)
+	}
+
+	return cookies, nil
+}
+
+// HTTPCookie converts the cookie to an http.Cookie, as set by a response from URL
+func (c *Cookie) HTTPCookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+	}
+
+	if c.Expires != nil {
+		cookie.Expires = *c.Expires
+	}
+
+	if !c.HostOnly {
+		cookie.Domain = c.Domain
+	}
+
+	return cookie
+}
+
+// URL returns a URL that the cookie could have been set by
+func (c *Cookie) URL() *url.URL {
+	u := &url.URL{
+		Scheme: "http",
+		Host:   strings.TrimPrefix(c.Domain, "."),
+		Path:   c.Path,
+	}
+
+	if c.Secure {
+		u.Scheme = "https"
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u
+}
@@ -0,0 +1,70 @@
+package postman
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookiesFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"postman.json": `[
+			{"name": "session", "value": "abc", "domain": "api.local", "path": "/", "expires": "Infinity", "hostOnly": true},
+			{"name": "pref", "value": "dark", "domain": ".api.local", "expires": "2030-01-02T03:04:05Z", "secure": true}
+		]`,
+		"newman.json": `{"version": "tough-cookie@2.5.0", "cookies": [
+			{"key": "session", "value": "abc", "domain": "api.local", "path": "/", "hostOnly": true},
+			{"key": "pref", "value": "dark", "domain": ".api.local", "expires": 1893553445, "secure": true}
+		]}`,
+	}
+
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		cookies, err := CookiesFromFile(path)
+		if !assert.NoError(t, err, name) || !assert.Len(t, cookies, 2, name) {
+			continue
+		}
+
+		assert.Equal(t, "session", cookies[0].Name, name)
+		assert.Nil(t, cookies[0].Expires, name)
+		assert.Equal(t, "http://api.local/", cookies[0].URL().String(), name)
+		assert.Equal(t, "", cookies[0].HTTPCookie().Domain, name)
+
+		assert.Equal(t, "pref", cookies[1].Name, name)
+		if assert.NotNil(t, cookies[1].Expires, name) {
+			assert.True(t, expires.Equal(*cookies[1].Expires), name)
+		}
+		assert.Equal(t, "https://api.local/", cookies[1].URL().String(), name)
+		assert.Equal(t, ".api.local", cookies[1].HTTPCookie().Domain, name)
+	}
+}
+
+func TestCookieMarshalJSON(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	data, err := json.Marshal([]Cookie{{Name: "session", Value: "abc", Domain: "api.local"}, {Name: "pref", Value: "dark", Domain: "api.local", Expires: &expires}})
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"name":"session","value":"abc","domain":"api.local"},{"name":"pref","value":"dark","domain":"api.local","expires":"2030-01-02T03:04:05Z"}]`, string(data))
+
+	cookies := []Cookie{}
+	assert.NoError(t, json.Unmarshal(data, &cookies))
+	assert.Nil(t, cookies[0].Expires)
+	assert.True(t, expires.Equal(*cookies[1].Expires))
+}
@@ -0,0 +1,189 @@
+package postman
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// encryptedHeader starts the first line of every encrypted file
+const encryptedHeader = "$GOPHERMAN_ENC;1;AES256-GCM"
+
+const (
+	saltSize      = 16
+	keySize       = 32
+	kdfIterations = 210000
+)
+
+// PassphraseEnvVar and KeyFileEnvVar name the OS environment variables used to
+// find the key for encrypted files when none is given explicitly
+const (
+	PassphraseEnvVar = "GOPHERMAN_PASSPHRASE"
+	KeyFileEnvVar    = "GOPHERMAN_KEY_FILE"
+)
+
+// IsEncrypted returns true if data was produced by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedHeader))
+}
+
+// Encrypt encrypts data with AES-256-GCM using a key derived from passphrase.
+// The output is text, so that encrypted files can be committed and diffed like any other
+func Encrypt(data, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, errors.Wrap(err, "failed to generate salt")
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	sealed := append(salt, nonce...)
+	sealed = gcm.Seal(sealed, nonce, data, []byte(encryptedHeader))
+
+	encoded := base64.StdEncoding.EncodeToString(sealed)
+
+	out := bytes.NewBufferString(encryptedHeader + "\n")
+	for len(encoded) > 64 {
+		out.WriteString(encoded[:64] + "\n")
+		encoded = encoded[64:]
+	}
+	out.WriteString(encoded + "\n")
+
+	return out.Bytes(), nil
+}
+
+// Decrypt decrypts data produced by Encrypt
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, errors.New("data is not encrypted")
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.Join(lines[1:], ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode encrypted data")
+	}
+
+	if len(sealed) < saltSize {
+		return nil, errors.New("encrypted data is truncated")
+	}
+
+	gcm, err := newGCM(passphrase, sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	sealed = sealed[saltSize:]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is truncated")
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(encryptedHeader))
+	if err != nil {
+		return nil, errors.New("failed to decrypt: wrong passphrase or corrupted data")
+	}
+
+	return plain, nil
+}
+
+// EncryptFile encrypts the file at in and writes it to out
+func EncryptFile(in, out string, passphrase []byte) error {
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+
+	if IsEncrypted(data) {
+		return fmt.Errorf("%s is already encrypted", in)
+	}
+
+	encrypted, err := Encrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(out, encrypted, 0600)
+}
+
+// DecryptFile decrypts the file at in and writes it to out
+func DecryptFile(in, out string, passphrase []byte) error {
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+
+	plain, err := Decrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(out, plain, 0600)
+}
+
+// KeyFromFile reads a passphrase from a key file, ignoring a trailing newline
+func KeyFromFile(filepath string) ([]byte, error) {
+	key, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read key file")
+	}
+
+	key = bytes.TrimRight(key, "\r\n")
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", filepath)
+	}
+
+	return key, nil
+}
+
+// PassphraseFromEnv returns the passphrase from $GOPHERMAN_PASSPHRASE, or else
+// from the key file named by $GOPHERMAN_KEY_FILE
+func PassphraseFromEnv() ([]byte, error) {
+	if pass := os.Getenv(PassphraseEnvVar); pass != "" {
+		return []byte(pass), nil
+	}
+
+	if keyFile := os.Getenv(KeyFileEnvVar); keyFile != "" {
+		return KeyFromFile(keyFile)
+	}
+
+	return nil, fmt.Errorf("file is encrypted but neither %s nor %s is set", PassphraseEnvVar, KeyFileEnvVar)
+}
+
+// newGCM derives a key from passphrase with PBKDF2-HMAC-SHA256 and returns an AES-GCM cipher using it
+func newGCM(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, kdfIterations, keySize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive key")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to NewCipher")
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,113 @@
+package postman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptKnownFile(t *testing.T) {
+	// encrypted with the key derivation gopherman has always used, so files encrypted by earlier versions still decrypt
+	encrypted := "$GOPHERMAN_ENC;1;AES256-GCM\n" +
+		"qW4uct9J5EwP//S5gcCQOgAdOgGQ1+Uq9HEpCBbSwKkoUVZvb+gKQbS6ggP56sd/\n" +
+		"ciz4wAn3BnH9D4W/50lcM8SbHk1h1XRnhmGG7OkYX/5upqYNUFxlBRg=\n"
+
+	plain, err := Decrypt([]byte(encrypted), []byte("passphrase"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"values":[{"key":"token","value":"s3cret"}]}`, string(plain))
+
+	_, err = Decrypt([]byte(encrypted), []byte("wrong"))
+	assert.Error(t, err)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"environment", `{"values":[{"key":"token","value":"s3cret","enabled":true}]}`},
+		{"binary", "\x00\xff\n\r\t"},
+	}
+
+	for _, test := range tests {
+		encrypted, err := Encrypt([]byte(test.data), []byte("passphrase"))
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		assert.True(t, IsEncrypted(encrypted), test.name)
+		assert.NotContains(t, string(encrypted), "s3cret", test.name)
+
+		plain, err := Decrypt(encrypted, []byte("passphrase"))
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.data, string(plain), test.name)
+	}
+}
+
+func TestEncryptSaltsEachFile(t *testing.T) {
+	first, err := Encrypt([]byte("data"), []byte("passphrase"))
+	assert.NoError(t, err)
+
+	second, err := Encrypt([]byte("data"), []byte("passphrase"))
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, string(first), string(second))
+}
+
+func TestDecryptErrors(t *testing.T) {
+	encrypted, err := Encrypt([]byte("data"), []byte("passphrase"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-3] ^= 1
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+	}{
+		{"wrong key", encrypted, "wrong"},
+		{"empty key", encrypted, ""},
+		{"tampered", tampered, "passphrase"},
+		{"not encrypted", []byte(`{"values":[]}`), "passphrase"},
+		{"truncated", encrypted[:len(encryptedHeader)+4], "passphrase"},
+	}
+
+	for _, test := range tests {
+		_, err := Decrypt(test.data, []byte(test.passphrase))
+		assert.Error(t, err, test.name)
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer os.RemoveAll(dir)
+
+	plainPath := filepath.Join(dir, "env.json")
+	encPath := filepath.Join(dir, "env.json.enc")
+	outPath := filepath.Join(dir, "out.json")
+
+	assert.NoError(t, ioutil.WriteFile(plainPath, []byte(`{"values":[]}`), 0600))
+	assert.NoError(t, EncryptFile(plainPath, encPath, []byte("passphrase")))
+	assert.Error(t, EncryptFile(encPath, encPath, []byte("passphrase")), "encrypting twice")
+
+	assert.Error(t, DecryptFile(encPath, outPath, []byte("wrong")))
+	_, err = os.Stat(outPath)
+	assert.True(t, os.IsNotExist(err), "a failed decrypt must not write its output")
+
+	assert.NoError(t, DecryptFile(encPath, outPath, []byte("passphrase")))
+
+	out, err := ioutil.ReadFile(outPath)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"values":[]}`, string(out))
+}
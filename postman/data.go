@@ -0,0 +1,108 @@
+package postman
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Data is iteration data: a collection or item is run once per row, with the row's values
+// in the data scope. It's the equivalent of Newman's -d option
+type Data struct {
+	// Fields are the column names, in file order for CSV and sorted for JSON
+	Fields []string
+	Rows   []map[string]string
+}
+
+// DataFromFile loads iteration data from a .csv file with a header row,
+// or a .json file containing an array of objects
+func DataFromFile(path string) (*Data, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return DataFromCSV(file)
+	case ".json":
+		return DataFromJSON(file)
+	}
+
+	return nil, fmt.Errorf("unknown data file type %s, expected .csv or .json", filepath.Ext(path))
+}
+
+// DataFromCSV parses CSV iteration data. The first row names the fields
+func DataFromCSV(file []byte) (*Data, error) {
+	records, err := csv.NewReader(bytes.NewReader(file)).ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read CSV")
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("CSV data has no header row")
+	}
+
+	data := &Data{
+		Fields: records[0],
+		Rows:   []map[string]string{},
+	}
+
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, field := range data.Fields {
+			row[field] = record[i]
+		}
+
+		data.Rows = append(data.Rows, row)
+	}
+
+	return data, nil
+}
+
+// DataFromJSON parses JSON iteration data, an array of objects.
+// Values that aren't strings are kept as their JSON text
+func DataFromJSON(file []byte) (*Data, error) {
+	objects := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(file, &objects); err != nil {
+		return nil, errors.Wrap(err, "JSON data must be an array of objects")
+	}
+
+	data := &Data{
+		Fields: []string{},
+		Rows:   []map[string]string{},
+	}
+
+	seen := map[string]bool{}
+
+	for _, obj := range objects {
+		row := map[string]string{}
+
+		for k, raw := range obj {
+			var str string
+			if err := json.Unmarshal(raw, &str); err == nil {
+				row[k] = str
+			} else {
+				row[k] = string(raw)
+			}
+
+			if !seen[k] {
+				seen[k] = true
+				data.Fields = append(data.Fields, k)
+			}
+		}
+
+		data.Rows = append(data.Rows, row)
+	}
+
+	sort.Strings(data.Fields)
+
+	return data, nil
+}
@@ -0,0 +1,60 @@
+package postman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"users.csv":  "name,id\nalice,1\n\"bob, jr\",2\n",
+		"users.JSON": `[{"name": "alice", "id": 1}, {"name": "bob, jr", "id": 2, "tags": ["a"]}]`,
+		"users.txt":  "name,id\n",
+		"empty.csv":  "",
+		"bad.csv":    "name,id\nalice\n",
+		"bad.json":   `{"name": "alice"}`,
+	}
+
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := DataFromFile(filepath.Join(dir, "users.csv"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"name", "id"}, data.Fields)
+		assert.Equal(t, []map[string]string{{"name": "alice", "id": "1"}, {"name": "bob, jr", "id": "2"}}, data.Rows)
+	}
+
+	data, err = DataFromFile(filepath.Join(dir, "users.JSON"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"id", "name", "tags"}, data.Fields)
+		assert.Equal(t, []map[string]string{{"name": "alice", "id": "1"}, {"name": "bob, jr", "id": "2", "tags": `["a"]`}}, data.Rows)
+	}
+
+	errs := map[string]string{
+		"users.txt":   "unknown data file type .txt",
+		"empty.csv":   "CSV data has no header row",
+		"bad.csv":     "failed to read CSV",
+		"bad.json":    "JSON data must be an array of objects",
+		"missing.csv": "no such file",
+	}
+
+	for name, msg := range errs {
+		_, err := DataFromFile(filepath.Join(dir, name))
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), msg, name)
+		}
+	}
+}
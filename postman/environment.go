@@ -3,25 +3,35 @@ package postman
 import (
 	"bytes"
 	"encoding/json"
-	"html/template"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 // Environment defines an execution environment
 type Environment struct {
 	EnvMeta
-	ID     string
-	Name   string
-	Values []Variable
+	ID     string     `json:"id"`
+	Name   string     `json:"name"`
+	Values []Variable `json:"values"`
 }
 
 // Variable defines a defined variable value
 type Variable struct {
-	Key         string
-	Value       string
-	Type        string
-	Description string
-	Enabled     bool
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Enabled     bool   `json:"enabled"`
+	Disabled    bool   `json:"disabled,omitempty"`
 }
 
 // EnvMeta defines the metadata for an environment
@@ -31,13 +41,46 @@ type EnvMeta struct {
 	ExportedUsing string `json:"_postman_exported_using"`
 }
 
-// EnvironmentFromFile creates an environment from a file
+// EnvironmentFromFile creates an environment from a file.
+// Encrypted files are decrypted using the passphrase from PassphraseFromEnv
 func EnvironmentFromFile(filepath string) (*Environment, error) {
 	file, err := ioutil.ReadFile(filepath)
 	if err != nil {
 		return nil, err
 	}
 
+	if IsEncrypted(file) {
+		passphrase, err := PassphraseFromEnv()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load %s", filepath)
+		}
+
+		return environmentFromEncrypted(file, passphrase)
+	}
+
+	return environmentFromJSON(file)
+}
+
+// EnvironmentFromEncryptedFile creates an environment from a file encrypted with passphrase
+func EnvironmentFromEncryptedFile(filepath string, passphrase []byte) (*Environment, error) {
+	file, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	return environmentFromEncrypted(file, passphrase)
+}
+
+func environmentFromEncrypted(file, passphrase []byte) (*Environment, error) {
+	plain, err := Decrypt(file, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return environmentFromJSON(plain)
+}
+
+func environmentFromJSON(file []byte) (*Environment, error) {
 	env := Environment{}
 	if err := json.Unmarshal(file, &env); err != nil {
 		return nil, err
@@ -46,6 +89,268 @@ func EnvironmentFromFile(filepath string) (*Environment, error) {
 	return &env, nil
 }
 
+// GlobalsFromFile loads a Postman globals export, which shares the environment format
+func GlobalsFromFile(filepath string) (*Environment, error) {
+	globals, err := EnvironmentFromFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	if globals.VariableScope == "" {
+		globals.VariableScope = "globals"
+	}
+
+	return globals, nil
+}
+
+// EnvironmentFromFiles loads a base environment and merges any override files into it, in order.
+// Override files that don't exist are skipped, so per-developer overrides can be left uncommitted
+func EnvironmentFromFiles(base string, overrides ...string) (*Environment, error) {
+	env, err := EnvironmentFromFile(base)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range overrides {
+		override, err := EnvironmentFromFile(path)
+		if err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				continue
+			}
+
+			return nil, errors.Wrapf(err, "failed to load override %s", path)
+		}
+
+		env = MergeEnvironments(env, override)
+	}
+
+	return env, nil
+}
+
+// MergeEnvironments returns a copy of base with the values of each override applied on top of it
+func MergeEnvironments(base *Environment, overrides ...*Environment) *Environment {
+	merged := *base
+	merged.Values = make([]Variable, len(base.Values))
+	copy(merged.Values, base.Values)
+
+	for _, override := range overrides {
+		for _, v := range override.Values {
+			merged.setVariable(v)
+		}
+	}
+
+	return &merged
+}
+
+// WriteFile saves the environment to a file in Postman's export format
+func (e *Environment) WriteFile(filepath string) error {
+	envJSON, err := e.export()
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath, envJSON, 0600); err != nil {
+		return errors.Wrap(err, "failed to WriteFile")
+	}
+
+	return nil
+}
+
+// WriteEncryptedFile saves the environment to a file in Postman's export format, encrypted with passphrase
+func (e *Environment) WriteEncryptedFile(filepath string, passphrase []byte) error {
+	envJSON, err := e.export()
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := Encrypt(envJSON, passphrase)
+	if err != nil {
+		return errors.Wrap(err, "failed to Encrypt")
+	}
+
+	if err := ioutil.WriteFile(filepath, encrypted, 0600); err != nil {
+		return errors.Wrap(err, "failed to WriteFile")
+	}
+
+	return nil
+}
+
+// export stamps the environment's metadata and marshals it in Postman's export format
+func (e *Environment) export() ([]byte, error) {
+	if e.VariableScope == "" {
+		e.VariableScope = "environment"
+	}
+
+	e.ExportedAt = time.Now().UTC().Format(time.RFC3339)
+	e.ExportedUsing = "gopherman"
+
+	envJSON, err := json.MarshalIndent(e, "", "\t")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to Marshal environment")
+	}
+
+	return envJSON, nil
+}
+
+// Get returns the value of an enabled variable
+func (e *Environment) Get(key string) (string, bool) {
+	for _, v := range e.Values {
+		if v.Key == key && v.Enabled {
+			return v.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Set sets the current value of a variable, adding it if it doesn't exist
+func (e *Environment) Set(key, value string) {
+	e.setVariable(Variable{Key: key, Value: value, Type: "default", Enabled: true})
+}
+
+// Overlay sets every value in vars, adding any that don't exist
+func (e *Environment) Overlay(vars map[string]string) {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+
+	// sorted so that newly added variables land in a stable order
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		e.Set(k, vars[k])
+	}
+}
+
+// OverlayOS overlays OS environment variables whose names start with prefix, with the prefix removed.
+// For example, with prefix GOPHERMAN_ENV_ the OS variable GOPHERMAN_ENV_apiKey sets apiKey
+func (e *Environment) OverlayOS(prefix string) {
+	e.Overlay(OSVariables(prefix))
+}
+
+// OverlayDotEnv overlays the values from a .env file
+func (e *Environment) OverlayDotEnv(filepath string) error {
+	vars, err := DotEnvFromFile(filepath)
+	if err != nil {
+		return err
+	}
+
+	e.Overlay(vars)
+
+	return nil
+}
+
+func (e *Environment) setVariable(variable Variable) {
+	for i, v := range e.Values {
+		if v.Key == variable.Key {
+			if variable.Type == "" {
+				variable.Type = v.Type
+			}
+
+			if variable.Description == "" {
+				variable.Description = v.Description
+			}
+
+			e.Values[i] = variable
+			return
+		}
+	}
+
+	e.Values = append(e.Values, variable)
+}
+
+// DotEnvFromFile reads KEY=VALUE pairs from a .env file.
+// Blank lines, # comments, an optional leading "export" and quotes around values are handled
+func DotEnvFromFile(filepath string) (map[string]string, error) {
+	file, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+
+	for i, line := range strings.Split(string(file), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		eq := strings.Index(line, "=")
+		if eq < 1 {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", filepath, i+1)
+		}
+
+		key := strings.TrimSpace(line[:eq])
+		val := strings.TrimSpace(line[eq+1:])
+
+		if val != "" && (val[0] == '"' || val[0] == '\'') {
+			end := closingQuote(val)
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unterminated quoted value", filepath, i+1)
+			}
+
+			if rest := strings.TrimSpace(val[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("%s:%d: unexpected %s after quoted value", filepath, i+1, rest)
+			}
+
+			if val[0] == '"' {
+				unquoted, err := strconv.Unquote(val[:end+1])
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: %s", filepath, i+1, err)
+				}
+
+				val = unquoted
+			} else {
+				val = val[1:end]
+			}
+		} else if hash := strings.Index(val, " #"); hash >= 0 {
+			val = strings.TrimSpace(val[:hash])
+		}
+
+		vars[key] = val
+	}
+
+	return vars, nil
+}
+
+// closingQuote returns the index of the quote that closes the one starting val, or -1.
+// Backslash escapes are skipped inside double quotes
+func closingQuote(val string) int {
+	for i := 1; i < len(val); i++ {
+		switch {
+		case val[0] == '"' && val[i] == '\\':
+			i++
+		case val[i] == val[0]:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// OSVariables returns the OS environment variables whose names start with prefix, with the prefix removed
+func OSVariables(prefix string) map[string]string {
+	vars := make(map[string]string)
+
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, prefix) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(kv, prefix), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+
+		vars[parts[0]] = parts[1]
+	}
+
+	return vars
+}
+
 // VariableMap returns a msp[string]string of the environment's variables
 func (e *Environment) VariableMap() map[string]string {
 	varMap := make(map[string]string)
@@ -59,18 +364,90 @@ func (e *Environment) VariableMap() map[string]string {
 	return varMap
 }
 
-// SubstVars subsitutes variables in a string
+// ResolvedVariableMap is like VariableMap, but resolves secret variables through secrets
+func (e *Environment) ResolvedVariableMap(secrets *Secrets) (map[string]string, error) {
+	varMap := make(map[string]string)
+
+	for _, v := range e.Values {
+		if !v.Enabled {
+			continue
+		}
+
+		val, err := secrets.Resolve(v)
+		if err != nil {
+			return nil, err
+		}
+
+		varMap[v.Key] = val
+	}
+
+	return varMap, nil
+}
+
+// postmanVarPattern matches Postman's {{name}} variable syntax
+var postmanVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_\-]*)\s*\}\}`)
+
+// templateKeywords can't be Postman variable names, since they're meaningful to text/template
+var templateKeywords = map[string]bool{
+	"if": true, "else": true, "end": true, "range": true, "with": true, "template": true,
+	"define": true, "block": true, "break": true, "continue": true, "nil": true, "true": true, "false": true,
+}
+
+// missingKeyPattern finds the key in text/template's error for a missing map entry
+var missingKeyPattern = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// UndefinedVariableError is returned by SubstVars for a variable that has no value
+type UndefinedVariableError struct {
+	Name string
+}
+
+func (u *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("undefined variable %s", u.Name)
+}
+
+// SubstVars subsitutes variables in a string. Both {{ .Name }} and Postman's {{name}} are supported.
+// A variable missing from vars is an *UndefinedVariableError rather than an empty string,
+// so that requests are never sent with values silently left out
 func SubstVars(templ string, vars map[string]string) (string, error) {
-	tmpl, err := template.New("gopherman").Parse(templ)
+	templ = postmanVarPattern.ReplaceAllStringFunc(templ, func(ref string) string {
+		name := postmanVarPattern.FindStringSubmatch(ref)[1]
+		if templateKeywords[name] {
+			return ref
+		}
+
+		return fmt.Sprintf("{{ variable %q }}", name)
+	})
+
+	// kept so that the error can be returned as is, rather than wrapped in the template's own
+	var undefined *UndefinedVariableError
+
+	variable := func(name string) (string, error) {
+		val, ok := vars[name]
+		if !ok {
+			undefined = &UndefinedVariableError{Name: name}
+			return "", undefined
+		}
+
+		return val, nil
+	}
+
+	tmpl, err := template.New("gopherman").Funcs(template.FuncMap{"variable": variable}).Option("missingkey=error").Parse(templ)
 	if err != nil {
 		return "", err
 	}
 
 	output := bytes.NewBuffer([]byte{})
 
-	err = tmpl.Execute(output, vars)
-	if err != nil {
-		panic(err)
+	if err := tmpl.Execute(output, vars); err != nil {
+		if undefined != nil {
+			return "", undefined
+		}
+
+		if match := missingKeyPattern.FindStringSubmatch(err.Error()); match != nil {
+			return "", &UndefinedVariableError{Name: match[1]}
+		}
+
+		return "", err
 	}
 
 	outBytes, err := ioutil.ReadAll(output)
@@ -0,0 +1,152 @@
+package postman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTemp writes contents to a file named name in a new temporary directory, returning its path
+func writeTemp(t *testing.T, name, contents string) string {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestDotEnvFromFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected map[string]string
+	}{
+		{"plain", "KEY=value", map[string]string{"KEY": "value"}},
+		{"blank lines and comments", "\n# comment\n  # indented comment\nKEY=value\n\n", map[string]string{"KEY": "value"}},
+		{"export", "export KEY=value", map[string]string{"KEY": "value"}},
+		{"spaces around", "  KEY =  value  ", map[string]string{"KEY": "value"}},
+		{"empty value", "KEY=", map[string]string{"KEY": ""}},
+		{"equals in value", "KEY=a=b", map[string]string{"KEY": "a=b"}},
+		{"trailing comment", "KEY=value # comment", map[string]string{"KEY": "value"}},
+		{"hash without space", "KEY=a#b", map[string]string{"KEY": "a#b"}},
+		{"double quotes", `KEY="a value"`, map[string]string{"KEY": "a value"}},
+		{"double quote escapes", `KEY="line\nnext \"quoted\""`, map[string]string{"KEY": "line\nnext \"quoted\""}},
+		{"single quotes are literal", `KEY='a\n # b'`, map[string]string{"KEY": `a\n # b`}},
+		{"comment after double quotes", `KEY="a # b" # comment`, map[string]string{"KEY": "a # b"}},
+		{"comment after single quotes", `KEY='a b' # comment`, map[string]string{"KEY": "a b"}},
+		{"empty quotes", `KEY=""`, map[string]string{"KEY": ""}},
+		{"crlf", "A=1\r\nB=\"2\"\r\n", map[string]string{"A": "1", "B": "2"}},
+		{"later wins", "KEY=1\nKEY=2", map[string]string{"KEY": "2"}},
+	}
+
+	for _, test := range tests {
+		path := writeTemp(t, ".env", test.contents)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		vars, err := DotEnvFromFile(path)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, vars, test.name)
+	}
+}
+
+func TestDotEnvFromFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"no equals", "KEY"},
+		{"no key", "=value"},
+		{"unterminated double quote", `KEY="value`},
+		{"unterminated single quote", `KEY='value`},
+		{"text after quotes", `KEY="a" b`},
+		{"escaped closing quote", `KEY="a\"`},
+	}
+
+	for _, test := range tests {
+		path := writeTemp(t, ".env", test.contents)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		_, err := DotEnvFromFile(path)
+		assert.Error(t, err, test.name)
+	}
+}
+
+func TestMergeEnvironments(t *testing.T) {
+	base := &Environment{Name: "base", Values: []Variable{
+		{Key: "host", Value: "staging", Type: "default", Description: "the host", Enabled: true},
+		{Key: "token", Value: "base", Type: "secret", Enabled: true},
+	}}
+
+	override := &Environment{Values: []Variable{
+		{Key: "token", Value: "mine", Enabled: true},
+		{Key: "debug", Value: "true", Enabled: true},
+	}}
+
+	merged := MergeEnvironments(base, override)
+
+	assert.Equal(t, map[string]string{"host": "staging", "token": "mine", "debug": "true"}, merged.VariableMap())
+	assert.Equal(t, "secret", merged.Values[1].Type, "the base's type is kept when the override has none")
+	assert.Equal(t, "base", base.Values[1].Value, "the base is not modified")
+}
+
+func TestOverlayDotEnv(t *testing.T) {
+	path := writeTemp(t, ".env", "token=\"from env\"\nnew=1\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	env := &Environment{Values: []Variable{{Key: "token", Value: "committed", Enabled: true}}}
+	assert.NoError(t, env.OverlayDotEnv(path))
+
+	token, _ := env.Get("token")
+	assert.Equal(t, "from env", token)
+
+	added, ok := env.Get("new")
+	assert.True(t, ok)
+	assert.Equal(t, "1", added)
+}
+
+func TestSubstVars(t *testing.T) {
+	vars := map[string]string{"id": "42", "Host": "api", "q": "a&b<c>"}
+
+	tests := []struct {
+		templ     string
+		expected  string
+		undefined string
+	}{
+		{"/users/{{id}}", "/users/42", ""},
+		{"/users/{{ id }}", "/users/42", ""},
+		{"{{ .Host }}/{{id}}", "api/42", ""},
+		{"?q={{q}}", "?q=a&b<c>", ""},
+		{"no variables", "no variables", ""},
+		{"{{ if .Host }}yes{{ end }}", "yes", ""},
+		{"/users/{{missing}}", "", "missing"},
+		{"{{ .Missing }}", "", "Missing"},
+		{"{{id}}/{{other}}", "", "other"},
+	}
+
+	for _, test := range tests {
+		out, err := SubstVars(test.templ, vars)
+
+		if test.undefined != "" {
+			if assert.IsType(t, &UndefinedVariableError{}, err, test.templ) {
+				assert.Equal(t, test.undefined, err.(*UndefinedVariableError).Name, test.templ)
+			}
+
+			continue
+		}
+
+		assert.NoError(t, err, test.templ)
+		assert.Equal(t, test.expected, out, test.templ)
+	}
+
+	_, err := SubstVars("{{ unclosed", vars)
+	assert.Error(t, err)
+}
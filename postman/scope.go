@@ -0,0 +1,202 @@
+package postman
+
+import "sync"
+
+// Scope holds variables at each of the levels Postman resolves them from.
+// When a key is defined at more than one level the narrowest one wins, in the
+// order Local, Data, Environment, Collection, Global.
+// Scope's methods are safe for concurrent use, but direct access to its maps is not.
+// A scope made by NewScope has a lock of its own, shared with its copies; any other
+// scope, such as a zero value, uses a lock shared by all such scopes
+type Scope struct {
+	Local       map[string]string
+	Data        map[string]string
+	Environment map[string]string
+	Collection  map[string]string
+	Global      map[string]string
+
+	// mu is shared by copies of the scope, since they share maps
+	mu *sync.RWMutex
+}
+
+// literalMu locks scopes that weren't made by NewScope
+var literalMu sync.RWMutex
+
+// NewScope returns an empty scope
+func NewScope() *Scope {
+	scope := Scope{
+		Local:       map[string]string{},
+		Data:        map[string]string{},
+		Environment: map[string]string{},
+		Collection:  map[string]string{},
+		Global:      map[string]string{},
+		mu:          &sync.RWMutex{},
+	}
+
+	return &scope
+}
+
+func (s *Scope) mutex() *sync.RWMutex {
+	if s.mu == nil {
+		return &literalMu
+	}
+
+	return s.mu
+}
+
+func (s *Scope) rlock() func() {
+	mu := s.mutex()
+
+	mu.RLock()
+	return mu.RUnlock
+}
+
+func (s *Scope) lock() func() {
+	mu := s.mutex()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// share returns a copy of the scope sharing its maps, creating any that are missing first
+// so that values set on the copy at those levels are shared too. s must be locked
+func (s *Scope) share() Scope {
+	for _, level := range []*map[string]string{&s.Local, &s.Data, &s.Environment, &s.Collection, &s.Global} {
+		if *level == nil {
+			*level = map[string]string{}
+		}
+	}
+
+	return *s
+}
+
+// levels returns the scope's variable maps from highest to lowest precedence
+func (s *Scope) levels() []map[string]string {
+	return []map[string]string{s.Local, s.Data, s.Environment, s.Collection, s.Global}
+}
+
+// Get returns the value of a variable from the narrowest level that defines it
+func (s *Scope) Get(key string) (string, bool) {
+	defer s.rlock()()
+
+	for _, level := range s.levels() {
+		if val, ok := level[key]; ok {
+			return val, true
+		}
+	}
+
+	return "", false
+}
+
+// Set sets a runtime (local) variable, which takes precedence over every other level
+func (s *Scope) Set(key, value string) {
+	defer s.lock()()
+
+	if s.Local == nil {
+		s.Local = map[string]string{}
+	}
+
+	s.Local[key] = value
+}
+
+// Unset removes a runtime (local) variable
+func (s *Scope) Unset(key string) {
+	defer s.lock()()
+
+	delete(s.Local, key)
+}
+
+// SetEnvironment sets a variable at the environment level
+func (s *Scope) SetEnvironment(key, value string) {
+	defer s.lock()()
+
+	if s.Environment == nil {
+		s.Environment = map[string]string{}
+	}
+
+	s.Environment[key] = value
+}
+
+// SetGlobal sets a variable at the global level
+func (s *Scope) SetGlobal(key, value string) {
+	defer s.lock()()
+
+	if s.Global == nil {
+		s.Global = map[string]string{}
+	}
+
+	s.Global[key] = value
+}
+
+// Map flattens the scope into a single map, applying precedence
+func (s *Scope) Map() map[string]string {
+	defer s.rlock()()
+
+	varMap := make(map[string]string)
+
+	levels := s.levels()
+	for i := len(levels) - 1; i >= 0; i-- {
+		for k, v := range levels[i] {
+			varMap[k] = v
+		}
+	}
+
+	return varMap
+}
+
+// LocalCopy returns a copy of the scope's runtime (local) variables
+func (s *Scope) LocalCopy() map[string]string {
+	defer s.rlock()()
+
+	local := make(map[string]string, len(s.Local))
+	for k, v := range s.Local {
+		local[k] = v
+	}
+
+	return local
+}
+
+// WithCollection returns a copy of the scope using vars as its collection level.
+// All other levels are shared with s, so runtime values set on the copy are kept
+func (s *Scope) WithCollection(vars map[string]string) *Scope {
+	defer s.lock()()
+
+	scope := s.share()
+	scope.Collection = vars
+
+	if scope.Collection == nil {
+		scope.Collection = map[string]string{}
+	}
+
+	return &scope
+}
+
+// WithData returns a copy of the scope using vars as its data level.
+// All other levels are shared with s, so runtime values set on the copy are kept
+func (s *Scope) WithData(vars map[string]string) *Scope {
+	defer s.lock()()
+
+	scope := s.share()
+	scope.Data = vars
+
+	if scope.Data == nil {
+		scope.Data = map[string]string{}
+	}
+
+	return &scope
+}
+
+// WithLocal returns a copy of the scope using vars as its local level, so runtime values
+// set on the copy are kept apart from s. All other levels are shared with s
+func (s *Scope) WithLocal(vars map[string]string) *Scope {
+	defer s.lock()()
+
+	scope := s.share()
+	scope.Local = vars
+
+	if scope.Local == nil {
+		scope.Local = map[string]string{}
+	}
+
+	return &scope
+}
@@ -0,0 +1,112 @@
+package postman
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeGet(t *testing.T) {
+	scope := NewScope()
+	scope.Global["a"] = "global"
+	scope.Collection["a"] = "collection"
+	scope.Collection["b"] = "collection"
+	scope.Environment["c"] = "environment"
+	scope.Set("c", "local")
+
+	cases := []struct {
+		key   string
+		val   string
+		found bool
+	}{
+		{"a", "collection", true},
+		{"b", "collection", true},
+		{"c", "local", true},
+		{"d", "", false},
+	}
+
+	for _, tc := range cases {
+		val, found := scope.Get(tc.key)
+		assert.Equal(t, tc.found, found, tc.key)
+		assert.Equal(t, tc.val, val, tc.key)
+	}
+
+	assert.Equal(t, map[string]string{"a": "collection", "b": "collection", "c": "local"}, scope.Map())
+}
+
+func TestScopeLocalCopy(t *testing.T) {
+	scope := NewScope()
+	scope.Set("id", "1")
+
+	local := scope.LocalCopy()
+	local["id"] = "2"
+
+	val, _ := scope.Get("id")
+	assert.Equal(t, "1", val)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				scope.Set(strconv.Itoa(i), strconv.Itoa(j))
+				scope.LocalCopy()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Len(t, scope.LocalCopy(), 9)
+}
+
+func TestScopeZeroValue(t *testing.T) {
+	scope := &Scope{}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				scope.Set(strconv.Itoa(i), strconv.Itoa(j))
+				scope.Get(strconv.Itoa(i))
+				scope.WithCollection(nil).Map()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Len(t, scope.Map(), 8)
+}
+
+func TestScopeCopiesShareLevels(t *testing.T) {
+	scope := &Scope{Global: map[string]string{"a": "global"}}
+
+	withCollection := scope.WithCollection(map[string]string{"b": "collection"})
+	withCollection.Set("id", "1")
+	withCollection.SetEnvironment("env", "1")
+
+	// runtime and environment values set on the copy are seen by the original
+	val, _ := scope.Get("id")
+	assert.Equal(t, "1", val)
+	val, _ = scope.Get("env")
+	assert.Equal(t, "1", val)
+
+	_, found := scope.Get("b")
+	assert.False(t, found)
+
+	withLocal := scope.WithLocal(nil)
+	withLocal.Set("id", "2")
+
+	val, _ = scope.Get("id")
+	assert.Equal(t, "1", val)
+	val, _ = withLocal.Get("a")
+	assert.Equal(t, "global", val)
+}
@@ -0,0 +1,232 @@
+package postman
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SecretType is the variable type Postman uses for secret values
+const SecretType = "secret"
+
+// secretMask replaces secret values in output
+const secretMask = "********"
+
+// refPattern matches a secret reference such as env:API_KEY or vault:kv/staging#token
+var refPattern = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):(.+)$`)
+
+// SecretProvider resolves a secret reference to its value
+type SecretProvider interface {
+	Secret(ref string) (string, error)
+}
+
+// EnvSecretProvider resolves secrets from OS environment variables, e.g. env:API_KEY
+type EnvSecretProvider struct{}
+
+// Secret returns the value of the environment variable named ref
+func (EnvSecretProvider) Secret(ref string) (string, error) {
+	val, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+
+	return val, nil
+}
+
+// FileSecretProvider resolves secrets from local files, e.g. file:secrets/token.
+// Relative paths are resolved against Dir, and a trailing newline is trimmed
+type FileSecretProvider struct {
+	Dir string
+}
+
+// Secret returns the contents of the file at ref
+func (f FileSecretProvider) Secret(ref string) (string, error) {
+	path := ref
+	if !filepath.IsAbs(path) && f.Dir != "" {
+		path = filepath.Join(f.Dir, path)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to ReadFile")
+	}
+
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
+
+// Secrets resolves secret variables through registered providers and remembers
+// the resolved values so they can be masked in output. It's safe for concurrent use
+type Secrets struct {
+	// Default is the scheme used for variables of type secret whose value has no scheme.
+	// When empty, such values are used as-is (but still masked)
+	Default string
+
+	providers map[string]SecretProvider
+	values    map[string]bool
+	mu        sync.RWMutex
+}
+
+// NewSecrets returns Secrets with the env: and file: providers registered
+func NewSecrets() *Secrets {
+	s := &Secrets{
+		providers: map[string]SecretProvider{},
+		values:    map[string]bool{},
+	}
+
+	s.Register("env", EnvSecretProvider{})
+	s.Register("file", FileSecretProvider{})
+
+	return s
+}
+
+// Register adds a provider for references starting with scheme:
+func (s *Secrets) Register(scheme string, provider SecretProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.providers[scheme] = provider
+}
+
+func (s *Secrets) provider(scheme string) (SecretProvider, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	provider, ok := s.providers[scheme]
+	return provider, ok
+}
+
+// Resolve returns the value of v, resolving it through a provider if it is a secret or a reference.
+// Values resolved this way are masked by Mask from then on
+func (s *Secrets) Resolve(v Variable) (string, error) {
+	isSecret := v.Type == SecretType
+
+	if match := refPattern.FindStringSubmatch(v.Value); match != nil && !strings.HasPrefix(match[2], "//") {
+		if provider, ok := s.provider(match[1]); ok {
+			val, err := provider.Secret(match[2])
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to resolve secret %s", v.Key)
+			}
+
+			s.remember(val)
+			return val, nil
+		}
+
+		if isSecret && s.Default == "" {
+			return "", fmt.Errorf("no secret provider registered for scheme %s (variable %s)", match[1], v.Key)
+		}
+	}
+
+	if !isSecret {
+		return v.Value, nil
+	}
+
+	if s.Default != "" {
+		provider, ok := s.provider(s.Default)
+		if !ok {
+			return "", fmt.Errorf("no secret provider registered for default scheme %s", s.Default)
+		}
+
+		val, err := provider.Secret(v.Value)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to resolve secret %s", v.Key)
+		}
+
+		s.remember(val)
+		return val, nil
+	}
+
+	s.remember(v.Value)
+	return v.Value, nil
+}
+
+// IsSecret returns true if val is a resolved secret value
+func (s *Secrets) IsSecret(val string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.values[val]
+}
+
+// Mask replaces every resolved secret value in str
+func (s *Secrets) Mask(str string) string {
+	if s == nil {
+		return str
+	}
+
+	s.mu.RLock()
+	vals := make([]string, 0, len(s.values))
+	for v := range s.values {
+		vals = append(vals, v)
+	}
+	s.mu.RUnlock()
+
+	// longest first, so a secret containing another is masked whole
+	sort.Slice(vals, func(i, j int) bool { return len(vals[i]) > len(vals[j]) })
+
+	for _, v := range vals {
+		str = strings.Replace(str, v, secretMask, -1)
+	}
+
+	return str
+}
+
+// MaskResponse returns a copy of resp with every resolved secret value replaced in its
+// body, headers, cookies and original request, or nil if resp is nil
+func (s *Secrets) MaskResponse(resp *Response) *Response {
+	if resp == nil {
+		return nil
+	}
+
+	masked := *resp
+	masked.Raw = s.Mask(resp.Raw)
+
+	masked.Header = make([]Header, len(resp.Header))
+	for i, h := range resp.Header {
+		h.Value = s.Mask(h.Value)
+		masked.Header[i] = h
+	}
+
+	masked.Cookie = make([]Cookie, len(resp.Cookie))
+	for i, c := range resp.Cookie {
+		c.Value = s.Mask(c.Value)
+		masked.Cookie[i] = c
+	}
+
+	if resp.OriginalRequest != nil {
+		req := *resp.OriginalRequest
+		req.URL.Raw = s.Mask(req.URL.Raw)
+		req.Body.Raw = s.Mask(req.Body.Raw)
+
+		req.Header = make([]Header, len(resp.OriginalRequest.Header))
+		for i, h := range resp.OriginalRequest.Header {
+			h.Value = s.Mask(h.Value)
+			req.Header[i] = h
+		}
+
+		masked.OriginalRequest = &req
+	}
+
+	return &masked
+}
+
+func (s *Secrets) remember(val string) {
+	if val == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[val] = true
+}
@@ -0,0 +1,171 @@
+package postman
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapProvider resolves secrets from a map
+type mapProvider map[string]string
+
+func (m mapProvider) Secret(ref string) (string, error) {
+	val, ok := m[ref]
+	if !ok {
+		return "", fmt.Errorf("no secret %s", ref)
+	}
+
+	return val, nil
+}
+
+func TestSecretsResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "token"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("GOPHERMAN_TEST_SECRET", "from-env")
+	defer os.Unsetenv("GOPHERMAN_TEST_SECRET")
+
+	tests := []struct {
+		name     string
+		def      string
+		variable Variable
+		val      string
+		masked   bool
+		err      string
+	}{
+		{name: "plain value", variable: Variable{Key: "host", Value: "localhost"}, val: "localhost"},
+		{name: "URL isn't a reference", variable: Variable{Key: "url", Value: "http://localhost"}, val: "http://localhost"},
+		{name: "unknown scheme isn't a reference", variable: Variable{Key: "mail", Value: "mailto:a@b.c"}, val: "mailto:a@b.c"},
+		{name: "secret value", variable: Variable{Key: "token", Value: "abc", Type: SecretType}, val: "abc", masked: true},
+		{name: "env reference", variable: Variable{Key: "token", Value: "env:GOPHERMAN_TEST_SECRET"}, val: "from-env", masked: true},
+		{name: "file reference", variable: Variable{Key: "token", Value: "file:" + filepath.Join(dir, "token")}, val: "from-file", masked: true},
+		{name: "registered provider", variable: Variable{Key: "token", Value: "vault:kv/token", Type: SecretType}, val: "from-vault", masked: true},
+		{name: "default provider", def: "vault", variable: Variable{Key: "token", Value: "kv/token", Type: SecretType}, val: "from-vault", masked: true},
+		{name: "default provider skips plain values", def: "vault", variable: Variable{Key: "host", Value: "localhost"}, val: "localhost"},
+		{name: "missing env variable", variable: Variable{Key: "token", Value: "env:GOPHERMAN_TEST_MISSING"}, err: "failed to resolve secret token: environment variable GOPHERMAN_TEST_MISSING is not set"},
+		{name: "provider error", variable: Variable{Key: "token", Value: "vault:kv/missing"}, err: "failed to resolve secret token: no secret kv/missing"},
+		{name: "unknown scheme for a secret", variable: Variable{Key: "token", Value: "aws:token", Type: SecretType}, err: "no secret provider registered for scheme aws (variable token)"},
+		{name: "unknown default scheme", def: "aws", variable: Variable{Key: "token", Value: "token", Type: SecretType}, err: "no secret provider registered for default scheme aws"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secrets := NewSecrets()
+			secrets.Register("vault", mapProvider{"kv/token": "from-vault"})
+			secrets.Default = test.def
+
+			val, err := secrets.Resolve(test.variable)
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.err, err.Error())
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.val, val)
+			assert.Equal(t, test.masked, secrets.IsSecret(val))
+
+			if test.masked {
+				assert.Equal(t, "token="+secretMask, secrets.Mask("token="+val))
+			}
+		})
+	}
+}
+
+func TestResolvedVariableMap(t *testing.T) {
+	secrets := NewSecrets()
+	secrets.Register("vault", mapProvider{"kv/token": "from-vault"})
+
+	collection := &Collection{Variable: []Variable{{Key: "host", Value: "localhost"}, {Key: "token", Value: "vault:kv/token"}}}
+
+	vars, err := collection.ResolvedVariableMap(secrets)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "localhost", "token": "from-vault"}, vars)
+
+	// resolved secrets can be substituted like any other value, but unknown {{$name}} dynamic variables can't
+	subst, err := SubstVars("Bearer {{token}}", vars)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer from-vault", subst)
+
+	_, err = SubstVars("{{$guid}}", vars)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "$guid")
+	}
+
+	collection.Variable = append(collection.Variable, Variable{Key: "missing", Value: "vault:kv/missing"})
+
+	_, err = collection.ResolvedVariableMap(secrets)
+	assert.Error(t, err)
+}
+
+func TestMaskResponse(t *testing.T) {
+	secrets := NewSecrets()
+	secrets.remember("s3cret")
+
+	resp := &Response{
+		Status: 200,
+		Raw:    `{"token":"s3cret"}`,
+		Header: []Header{{Key: "Set-Cookie", Value: "session=s3cret"}, {Key: "Content-Type", Value: "application/json"}},
+		Cookie: []Cookie{{Name: "session", Value: "s3cret"}},
+		OriginalRequest: &Request{
+			URL:    URL{Raw: "/login?key=s3cret"},
+			Header: []Header{{Key: "Authorization", Value: "Bearer s3cret"}},
+			Body:   Body{Raw: "password=s3cret"},
+		},
+	}
+
+	masked := secrets.MaskResponse(resp)
+
+	assert.Equal(t, `{"token":"`+secretMask+`"}`, masked.Raw)
+	assert.Equal(t, "session="+secretMask, masked.Header[0].Value)
+	assert.Equal(t, "application/json", masked.Header[1].Value)
+	assert.Equal(t, secretMask, masked.Cookie[0].Value)
+	assert.Equal(t, "/login?key="+secretMask, masked.OriginalRequest.URL.Raw)
+	assert.Equal(t, "Bearer "+secretMask, masked.OriginalRequest.Header[0].Value)
+	assert.Equal(t, "password="+secretMask, masked.OriginalRequest.Body.Raw)
+
+	assert.Equal(t, `{"token":"s3cret"}`, resp.Raw, "the original is not modified")
+	assert.Equal(t, "session=s3cret", resp.Header[0].Value, "the original is not modified")
+	assert.Equal(t, "s3cret", resp.Cookie[0].Value, "the original is not modified")
+	assert.Equal(t, "Bearer s3cret", resp.OriginalRequest.Header[0].Value, "the original is not modified")
+
+	assert.Nil(t, secrets.MaskResponse(nil))
+}
+
+func TestSecretsConcurrent(t *testing.T) {
+	secrets := NewSecrets()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				val := fmt.Sprintf("secret-%d-%d", i, j)
+
+				resolved, err := secrets.Resolve(Variable{Key: "k", Value: val, Type: SecretType})
+				assert.NoError(t, err)
+				assert.Equal(t, val, resolved)
+
+				assert.Equal(t, secretMask, secrets.Mask(val))
+				assert.True(t, secrets.IsSecret(val))
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
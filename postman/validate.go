@@ -0,0 +1,241 @@
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// varRefPattern matches variable references, both gopherman's {{ .Name }} and Postman's {{name}}
+var varRefPattern = regexp.MustCompile(`\{\{\s*\.?([A-Za-z_][A-Za-z0-9_\-]*)\s*\}\}`)
+
+// knownSchemas are the collection schemas gopherman understands
+var knownSchemas = []string{
+	"https://schema.getpostman.com/json/collection/v2.1.0/collection.json",
+	"https://schema.getpostman.com/json/collection/v2.0.0/collection.json",
+}
+
+var validMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "COPY": true, "HEAD": true,
+	"OPTIONS": true, "LINK": true, "UNLINK": true, "PURGE": true, "LOCK": true, "UNLOCK": true,
+	"PROPFIND": true, "VIEW": true,
+}
+
+var validBodyModes = map[string]bool{
+	"": true, "raw": true, "urlencoded": true, "formdata": true, "file": true, "graphql": true,
+}
+
+// ValidationError describes a problem with a collection and where in the collection it is
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (v ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// ValidateJSON unmarshals a collection and validates it. See Validate
+func ValidateJSON(data []byte, vars map[string]string) []ValidationError {
+	collection := Collection{}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return []ValidationError{jsonError(data, err)}
+	}
+
+	return Validate(&collection, vars)
+}
+
+// Validate checks a collection against the v2.1 schema rules and gopherman's own requirements,
+// returning every problem found. Variable references are checked against the collection's own
+// variables and vars; pass nil vars to skip checking for undefined variables
+func Validate(c *Collection, vars map[string]string) []ValidationError {
+	v := validator{
+		names: map[string]string{},
+	}
+
+	if vars != nil {
+		v.vars = c.VariableMap()
+		for k, val := range vars {
+			v.vars[k] = val
+		}
+	}
+
+	if c.Info.Name == "" {
+		v.add("$.info.name", "collection has no name")
+	}
+
+	if c.Info.Schema == "" {
+		v.add("$.info.schema", "collection has no schema")
+	} else if !isKnownSchema(c.Info.Schema) {
+		v.add("$.info.schema", fmt.Sprintf("unsupported schema %s, expected collection v2.1.0", c.Info.Schema))
+	}
+
+	for i, variable := range c.Variable {
+		if variable.Key == "" {
+			v.add(fmt.Sprintf("$.variable[%d].key", i), "variable has no key")
+		}
+	}
+
+	if len(c.Item) == 0 {
+		v.add("$.item", "collection has no items")
+	}
+
+	v.items("$", c.Item)
+
+	return v.errs
+}
+
+type validator struct {
+	vars  map[string]string
+	names map[string]string
+	errs  []ValidationError
+}
+
+func (v *validator) add(path, msg string) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: msg})
+}
+
+func (v *validator) items(parent string, items []CollectionItem) {
+	for i := range items {
+		itm := &items[i]
+		path := fmt.Sprintf("%s.item[%d]", parent, i)
+
+		if itm.Name == "" {
+			v.add(path+".name", "item has no name")
+		} else if first, ok := v.names[itm.Name]; ok {
+			v.add(path+".name", fmt.Sprintf("duplicate item name %q, first used at %s", itm.Name, first))
+		} else {
+			v.names[itm.Name] = path
+		}
+
+		if itm.IsFolder() {
+			if itm.Request.Method != "" || itm.Request.URL.Raw != "" {
+				v.add(path, "item is both a folder and a request")
+			}
+
+			v.items(path, itm.Item)
+			continue
+		}
+
+		v.request(path+".request", &itm.Request)
+
+		if len(itm.Response) == 0 && !itm.HasSchema() {
+			v.add(path+".response", "item has no example responses")
+		}
+
+		for j, resp := range itm.Response {
+			if resp.Status == 0 {
+				v.add(fmt.Sprintf("%s.response[%d].code", path, j), "example has no status code")
+			}
+		}
+	}
+}
+
+func (v *validator) request(path string, req *Request) {
+	if req.Method == "" {
+		v.add(path+".method", "request has no method")
+	} else if !validMethods[strings.ToUpper(req.Method)] {
+		v.add(path+".method", fmt.Sprintf("unknown method %s", req.Method))
+	}
+
+	if req.URL.Raw == "" {
+		v.add(path+".url.raw", "request has no URL")
+	} else {
+		// variables can't be resolved here, so stand in a placeholder that parses anywhere in a URL
+		placeholder := varRefPattern.ReplaceAllString(req.URL.Raw, "placeholder")
+		if _, err := url.Parse(placeholder); err != nil {
+			v.add(path+".url.raw", fmt.Sprintf("unparsable URL: %s", err))
+		}
+	}
+
+	v.refs(path+".url.raw", req.URL.Raw)
+
+	for i, h := range req.Header {
+		hpath := fmt.Sprintf("%s.header[%d]", path, i)
+
+		if h.Key == "" {
+			v.add(hpath+".key", "header has no key")
+		}
+
+		v.refs(hpath+".key", h.Key)
+		v.refs(hpath+".value", h.Value)
+	}
+
+	if !validBodyModes[req.Body.Mode] {
+		v.add(path+".body.mode", fmt.Sprintf("unknown body mode %s", req.Body.Mode))
+	}
+
+	v.refs(path+".body.raw", req.Body.Raw)
+}
+
+// refs checks that every variable referenced in str is defined
+func (v *validator) refs(path, str string) {
+	if v.vars == nil {
+		return
+	}
+
+	for _, name := range VariableRefs(str) {
+		if _, ok := v.vars[name]; !ok {
+			v.add(path, fmt.Sprintf("undefined variable %s", name))
+		}
+	}
+}
+
+// VariableRefs returns the names of the variables referenced in str
+func VariableRefs(str string) []string {
+	names := []string{}
+
+	for _, match := range varRefPattern.FindAllStringSubmatch(str, -1) {
+		names = append(names, match[1])
+	}
+
+	return names
+}
+
+func isKnownSchema(schema string) bool {
+	for _, s := range knownSchemas {
+		if s == schema {
+			return true
+		}
+	}
+
+	return false
+}
+
+// jsonError converts a json.Unmarshal error into a ValidationError, with the best location available
+func jsonError(data []byte, err error) ValidationError {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		line, col := lineCol(data, e.Offset)
+		return ValidationError{Path: "$", Message: fmt.Sprintf("invalid JSON at line %d, column %d: %s", line, col, e)}
+	case *json.UnmarshalTypeError:
+		path := "$"
+		if e.Field != "" {
+			for _, field := range strings.Split(e.Field, ".") {
+				path += "." + strings.ToLower(field[:1]) + field[1:]
+			}
+		}
+
+		line, col := lineCol(data, e.Offset)
+		return ValidationError{Path: path, Message: fmt.Sprintf("expected %s but found %s at line %d, column %d", e.Type, e.Value, line, col)}
+	}
+
+	return ValidationError{Path: "$", Message: err.Error()}
+}
+
+func lineCol(data []byte, offset int64) (int, int) {
+	line, col := 1, 1
+
+	for i := int64(0); i < offset && i < int64(len(data)); i++ {
+		if data[i] == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+
+	return line, col
+}
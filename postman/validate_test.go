@@ -0,0 +1,116 @@
+package postman
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const v21Schema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+func validItem(name string) CollectionItem {
+	return CollectionItem{
+		Name:     name,
+		Request:  Request{Method: "GET", URL: URL{Raw: "{{BaseUrl}}/" + name}},
+		Response: []Response{{Status: 200}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Collection {
+		return &Collection{
+			Info:     CollectionInfo{Name: "test", Schema: v21Schema},
+			Item:     []CollectionItem{validItem("a"), {Name: "folder", Item: []CollectionItem{validItem("b")}}},
+			Variable: []Variable{{Key: "BaseUrl", Value: "http://localhost"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Collection)
+		vars   map[string]string
+		errs   []ValidationError
+	}{
+		{
+			name:   "valid",
+			modify: func(c *Collection) {},
+			vars:   map[string]string{},
+		},
+		{
+			name:   "missing collection name and schema",
+			modify: func(c *Collection) { c.Info = CollectionInfo{} },
+			errs: []ValidationError{
+				{Path: "$.info.name", Message: "collection has no name"},
+				{Path: "$.info.schema", Message: "collection has no schema"},
+			},
+		},
+		{
+			name:   "missing item name and URL",
+			modify: func(c *Collection) { c.Item[0].Name = ""; c.Item[0].Request.URL.Raw = "" },
+			errs: []ValidationError{
+				{Path: "$.item[0].name", Message: "item has no name"},
+				{Path: "$.item[0].request.url.raw", Message: "request has no URL"},
+			},
+		},
+		{
+			name:   "duplicate names",
+			modify: func(c *Collection) { c.Item[1].Item[0].Name = "a" },
+			errs: []ValidationError{
+				{Path: "$.item[1].item[0].name", Message: `duplicate item name "a", first used at $.item[0]`},
+			},
+		},
+		{
+			name:   "bad method",
+			modify: func(c *Collection) { c.Item[0].Request.Method = "FETCH" },
+			errs: []ValidationError{
+				{Path: "$.item[0].request.method", Message: "unknown method FETCH"},
+			},
+		},
+		{
+			name: "undefined variable",
+			modify: func(c *Collection) {
+				c.Item[0].Request.Header = []Header{{Key: "Authorization", Value: "Bearer {{token}}"}}
+			},
+			vars: map[string]string{},
+			errs: []ValidationError{
+				{Path: "$.item[0].request.header[0].value", Message: "undefined variable token"},
+			},
+		},
+		{
+			name: "variables from vars",
+			modify: func(c *Collection) {
+				c.Item[0].Request.Header = []Header{{Key: "Authorization", Value: "Bearer {{token}}"}}
+			},
+			vars: map[string]string{"token": "abc"},
+		},
+		{
+			name:   "missing example status",
+			modify: func(c *Collection) { c.Item[0].Response[0].Status = 0 },
+			errs: []ValidationError{
+				{Path: "$.item[0].response[0].code", Message: "example has no status code"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := valid()
+			test.modify(c)
+
+			assert.Equal(t, test.errs, Validate(c, test.vars))
+		})
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	errs := ValidateJSON([]byte("{\n\t\"info\": {\"name\": 1}\n}"), nil)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "$.info.name", errs[0].Path)
+		assert.Contains(t, errs[0].Message, "line 2")
+	}
+
+	errs = ValidateJSON([]byte(`{"info": `), nil)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "$", errs[0].Path)
+	}
+}
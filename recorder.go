@@ -62,10 +62,22 @@ func (rr *RequestRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fakeWriter := NewFakeWriter(r.Header)
+	fakeWriter := NewFakeWriter(http.Header{})
+
+	start := time.Now()
 
 	rr.mux.ServeHTTP(fakeWriter, r)
 
+	responseTime := time.Since(start).Milliseconds()
+
+	for k, vals := range fakeWriter.Header() {
+		w.Header()[k] = vals
+	}
+
+	if fakeWriter.StatusCode == 0 {
+		fakeWriter.StatusCode = http.StatusOK
+	}
+
 	w.WriteHeader(fakeWriter.StatusCode)
 
 	item := postman.CollectionItem{
@@ -79,13 +91,21 @@ func (rr *RequestRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		item.Response = []postman.Response{
-			postman.Response{
-				Mode:   "raw",
-				Raw:    string(fakeWriter.Body),
-				Status: fakeWriter.StatusCode,
-			},
+		resp := postman.Response{
+			Mode:         "raw",
+			Raw:          string(fakeWriter.Body),
+			Status:       fakeWriter.StatusCode,
+			StatusText:   http.StatusText(fakeWriter.StatusCode),
+			Header:       postman.HeadersFromHTTP(fakeWriter.Header()),
+			Cookie:       []postman.Cookie{},
+			ResponseTime: responseTime,
+		}
+
+		for _, c := range (&http.Response{Header: fakeWriter.Header()}).Cookies() {
+			resp.Cookie = append(resp.Cookie, postman.CookieFromHTTP(c, r.Host))
 		}
+
+		item.Response = []postman.Response{resp}
 	}
 
 	rr.reqs = append(rr.reqs, item)
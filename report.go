@@ -0,0 +1,611 @@
+package gopherman
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// Result is the outcome of running one item
+type Result struct {
+	Collection string
+	// Path is the item's folder path and name, e.g. users/create
+	Path     string
+	Start    time.Time
+	Duration time.Duration
+	Attempts int
+	Errors   []string
+	// Skipped is true if the item had no examples and Tester.SkipMissingExamples is set
+	Skipped bool
+
+	Request  *RequestDump
+	Expected *postman.Response
+	Response *postman.Response
+}
+
+// Passed returns true if the item had no errors
+func (r Result) Passed() bool {
+	return len(r.Errors) == 0
+}
+
+// RequestDump is a copy of a request as it was sent
+type RequestDump struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+}
+
+// Report holds the results of every item run by a Tester
+type Report struct {
+	Start   time.Time
+	End     time.Time
+	Results []Result
+}
+
+// Summary counts a report's results
+type Summary struct {
+	Total   int
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// Summary counts the report's results
+func (r *Report) Summary() Summary {
+	s := Summary{Total: len(r.Results)}
+
+	for i := range r.Results {
+		if r.Results[i].Skipped {
+			s.Skipped++
+		} else if r.Results[i].Passed() {
+			s.Passed++
+		} else {
+			s.Failed++
+		}
+	}
+
+	return s
+}
+
+// Collections returns the names of the collections in the report, in the order they were first run
+func (r *Report) Collections() []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	for _, res := range r.Results {
+		if !seen[res.Collection] {
+			seen[res.Collection] = true
+			names = append(names, res.Collection)
+		}
+	}
+
+	return names
+}
+
+// Reporter writes a report somewhere, such as a file for CI to pick up
+type Reporter interface {
+	Report(report *Report) error
+}
+
+// resetReport starts a new, empty report
+func (t *Tester) resetReport() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.report = &Report{Start: time.Now()}
+}
+
+// Report returns a copy of the results recorded so far in the current or last run
+func (t *Tester) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.report == nil {
+		return &Report{Start: time.Now(), End: time.Now()}
+	}
+
+	report := *t.report
+	report.Results = append([]Result{}, t.report.Results...)
+
+	return &report
+}
+
+// WriteReports gives the results recorded so far to each of t.Reporters
+func (t *Tester) WriteReports() error {
+	if len(t.Reporters) == 0 {
+		return nil
+	}
+
+	report := t.Report()
+
+	for _, r := range t.Reporters {
+		if err := r.Report(report); err != nil {
+			return errors.Wrap(err, "failed to write report")
+		}
+	}
+
+	return nil
+}
+
+// record adds the result of an item to the tester's report
+func (t *Tester) record(result *Result, helper *TestHelper) {
+	if result.Duration == 0 {
+		result.Duration = time.Since(result.Start)
+	}
+
+	result.Attempts = helper.Attempts
+
+	for _, e := range helper.errors {
+		result.Errors = append(result.Errors, t.Secrets.Mask(e.Error()))
+	}
+
+	result.Errors = append(result.Errors, helper.failures...)
+
+	if result.Request != nil {
+		result.Request = result.Request.masked(t.Secrets)
+	}
+
+	// responses can echo secrets back, such as in a Set-Cookie header
+	result.Expected = t.Secrets.MaskResponse(result.Expected)
+	result.Response = t.Secrets.MaskResponse(result.Response)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.report == nil {
+		t.report = &Report{Start: result.Start}
+	}
+
+	t.report.Results = append(t.report.Results, *result)
+	t.report.End = time.Now()
+}
+
+func dumpRequest(req *http.Request) *RequestDump {
+	dump := &RequestDump{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			dump.Body = string(b)
+		}
+	}
+
+	return dump
+}
+
+func (d *RequestDump) masked(secrets *postman.Secrets) *RequestDump {
+	masked := &RequestDump{
+		Method: d.Method,
+		URL:    secrets.Mask(d.URL),
+		Header: http.Header{},
+		Body:   secrets.Mask(d.Body),
+	}
+
+	for k, vals := range d.Header {
+		for _, v := range vals {
+			masked.Header.Add(k, secrets.Mask(v))
+		}
+	}
+
+	return masked
+}
+
+// String renders the request in HTTP/1.1 style
+func (d *RequestDump) String() string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s\n", d.Method, d.URL)
+
+	keys := []string{}
+	for k := range d.Header {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range d.Header[k] {
+			fmt.Fprintf(buf, "%s: %s\n", k, v)
+		}
+	}
+
+	if d.Body != "" {
+		fmt.Fprintf(buf, "\n%s\n", d.Body)
+	}
+
+	return buf.String()
+}
+
+// failureDump describes a failed result's errors, request and response for a report
+func failureDump(res *Result) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString(strings.Join(res.Errors, "\n"))
+
+	if res.Request != nil {
+		fmt.Fprintf(buf, "\n\n--- request ---\n%s", res.Request)
+	}
+
+	if res.Response != nil {
+		fmt.Fprintf(buf, "\n--- response ---\nstatus %d\n", res.Response.Status)
+
+		for _, h := range res.Response.Header {
+			fmt.Fprintf(buf, "%s: %s\n", h.Key, h.Value)
+		}
+
+		fmt.Fprintf(buf, "\n%s\n", res.Response.Raw)
+	}
+
+	return buf.String()
+}
+
+////////// JUnit //////////
+
+// JUnitReporter writes a JUnit XML report, with one test suite per collection
+type JUnitReporter struct {
+	Path string
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     float64      `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      float64     `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Report writes the report to r.Path
+func (r *JUnitReporter) Report(report *Report) error {
+	summary := report.Summary()
+
+	suites := junitSuites{
+		Tests:    summary.Total,
+		Failures: summary.Failed,
+		Skipped:  summary.Skipped,
+		Time:     report.End.Sub(report.Start).Seconds(),
+	}
+
+	for _, name := range report.Collections() {
+		suite := junitSuite{Name: name}
+
+		for i := range report.Results {
+			res := &report.Results[i]
+			if res.Collection != name {
+				continue
+			}
+
+			if suite.Timestamp == "" {
+				suite.Timestamp = res.Start.UTC().Format("2006-01-02T15:04:05")
+			}
+
+			tc := junitCase{
+				Name:      res.Path,
+				Classname: name,
+				Time:      res.Duration.Seconds(),
+			}
+
+			if res.Skipped {
+				tc.Skipped = &junitSkipped{Message: "no example responses"}
+				suite.Skipped++
+			} else if !res.Passed() {
+				tc.Failure = &junitFailure{Message: strings.SplitN(res.Errors[0], "\n", 2)[0], Text: failureDump(res)}
+				suite.Failures++
+			}
+
+			suite.Tests++
+			suite.Time += tc.Time
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	out, err := xml.MarshalIndent(suites, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to Marshal JUnit report")
+	}
+
+	return ioutil.WriteFile(r.Path, append([]byte(xml.Header), out...), 0644)
+}
+
+////////// JSON //////////
+
+// JSONReporter writes a JSON report in the shape of Newman's JSON reporter
+type JSONReporter struct {
+	Path string
+}
+
+type newmanReport struct {
+	Collection newmanCollection `json:"collection"`
+	Run        newmanRun        `json:"run"`
+}
+
+type newmanCollection struct {
+	Info newmanInfo `json:"info"`
+}
+
+type newmanInfo struct {
+	Name string `json:"name"`
+}
+
+type newmanRun struct {
+	Stats      newmanStats       `json:"stats"`
+	Timings    newmanTimings     `json:"timings"`
+	Executions []newmanExecution `json:"executions"`
+	Failures   []newmanFailure   `json:"failures"`
+}
+
+type newmanStats struct {
+	Requests   newmanStat `json:"requests"`
+	Assertions newmanStat `json:"assertions"`
+}
+
+type newmanStat struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Failed  int `json:"failed"`
+}
+
+type newmanTimings struct {
+	Started         int64   `json:"started"`
+	Completed       int64   `json:"completed"`
+	ResponseAverage float64 `json:"responseAverage"`
+	ResponseMin     float64 `json:"responseMin"`
+	ResponseMax     float64 `json:"responseMax"`
+}
+
+type newmanExecution struct {
+	Cursor     newmanCursor      `json:"cursor"`
+	Item       newmanInfo        `json:"item"`
+	Request    *newmanRequest    `json:"request,omitempty"`
+	Response   *newmanResponse   `json:"response,omitempty"`
+	Assertions []newmanAssertion `json:"assertions"`
+}
+
+type newmanCursor struct {
+	Ref      string `json:"ref"`
+	Position int    `json:"position"`
+	Started  string `json:"started"`
+}
+
+type newmanRequest struct {
+	Method string         `json:"method"`
+	URL    string         `json:"url"`
+	Header []newmanHeader `json:"header"`
+	Body   string         `json:"body,omitempty"`
+}
+
+type newmanHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type newmanResponse struct {
+	Code         int            `json:"code"`
+	Status       string         `json:"status"`
+	Header       []newmanHeader `json:"header"`
+	Body         string         `json:"body"`
+	ResponseTime float64        `json:"responseTime"`
+	ResponseSize int            `json:"responseSize"`
+}
+
+type newmanAssertion struct {
+	Assertion string       `json:"assertion"`
+	Skipped   bool         `json:"skipped,omitempty"`
+	Error     *newmanError `json:"error,omitempty"`
+}
+
+type newmanError struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+type newmanFailure struct {
+	Error  newmanError `json:"error"`
+	Source newmanInfo  `json:"source"`
+	At     string      `json:"at"`
+}
+
+// Report writes the report to r.Path
+func (r *JSONReporter) Report(report *Report) error {
+	out := newmanReport{
+		Collection: newmanCollection{Info: newmanInfo{Name: strings.Join(report.Collections(), ", ")}},
+		Run: newmanRun{
+			Timings: newmanTimings{
+				Started:   report.Start.UnixNano() / int64(time.Millisecond),
+				Completed: report.End.UnixNano() / int64(time.Millisecond),
+			},
+			Executions: []newmanExecution{},
+			Failures:   []newmanFailure{},
+		},
+	}
+
+	var totalTime float64
+
+	for i := range report.Results {
+		res := &report.Results[i]
+		ms := float64(res.Duration) / float64(time.Millisecond)
+
+		exec := newmanExecution{
+			Cursor:     newmanCursor{Ref: res.Collection + "/" + res.Path, Position: i, Started: res.Start.UTC().Format(time.RFC3339Nano)},
+			Item:       newmanInfo{Name: res.Path},
+			Assertions: []newmanAssertion{},
+		}
+
+		if res.Request != nil {
+			exec.Request = &newmanRequest{Method: res.Request.Method, URL: res.Request.URL, Body: res.Request.Body, Header: []newmanHeader{}}
+			for k, vals := range res.Request.Header {
+				for _, v := range vals {
+					exec.Request.Header = append(exec.Request.Header, newmanHeader{Key: k, Value: v})
+				}
+			}
+		}
+
+		if res.Response != nil {
+			exec.Response = &newmanResponse{
+				Code:         res.Response.Status,
+				Status:       http.StatusText(res.Response.Status),
+				Header:       []newmanHeader{},
+				Body:         res.Response.Raw,
+				ResponseTime: ms,
+				ResponseSize: len(res.Response.Raw),
+			}
+
+			if res.Response.StatusText != "" {
+				exec.Response.Status = res.Response.StatusText
+			}
+
+			for _, h := range res.Response.Header {
+				exec.Response.Header = append(exec.Response.Header, newmanHeader{Key: h.Key, Value: h.Value})
+			}
+
+			out.Run.Stats.Requests.Total++
+			totalTime += ms
+
+			if out.Run.Timings.ResponseMin == 0 || ms < out.Run.Timings.ResponseMin {
+				out.Run.Timings.ResponseMin = ms
+			}
+
+			if ms > out.Run.Timings.ResponseMax {
+				out.Run.Timings.ResponseMax = ms
+			}
+		}
+
+		assertion := newmanAssertion{Assertion: "response matches example", Skipped: res.Skipped}
+		out.Run.Stats.Assertions.Total++
+
+		if res.Skipped {
+			out.Run.Stats.Assertions.Pending++
+		} else if !res.Passed() {
+			assertion.Error = &newmanError{Name: "AssertionError", Message: strings.Join(res.Errors, "\n")}
+			out.Run.Stats.Assertions.Failed++
+
+			out.Run.Failures = append(out.Run.Failures, newmanFailure{
+				Error:  *assertion.Error,
+				Source: newmanInfo{Name: res.Path},
+				At:     res.Collection + "/" + res.Path,
+			})
+		}
+
+		exec.Assertions = append(exec.Assertions, assertion)
+		out.Run.Executions = append(out.Run.Executions, exec)
+	}
+
+	if out.Run.Stats.Requests.Total > 0 {
+		out.Run.Timings.ResponseAverage = totalTime / float64(out.Run.Stats.Requests.Total)
+	}
+
+	outJSON, err := json.MarshalIndent(out, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to Marshal JSON report")
+	}
+
+	return ioutil.WriteFile(r.Path, outJSON, 0644)
+}
+
+////////// HTML //////////
+
+// HTMLReporter writes a self-contained HTML report
+type HTMLReporter struct {
+	Path string
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms":   func(d time.Duration) string { return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond)) },
+	"dump": func(res Result) string { return failureDump(&res) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gopherman report</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+.summary span { display: inline-block; margin-right: 2em; font-size: 1.2em; }
+.passed { color: #217a3c; } .failed { color: #b3261e; }
+table { border-collapse: collapse; width: 100%; margin-top: 1em; }
+th, td { text-align: left; padding: 0.4em 0.8em; border-bottom: 1px solid #ddd; vertical-align: top; }
+pre { background: #f6f6f6; padding: 0.8em; overflow-x: auto; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>gopherman report</h1>
+<p>{{ .Report.Start.Format "2006-01-02 15:04:05" }} &ndash; {{ .Report.End.Format "2006-01-02 15:04:05" }}</p>
+<div class="summary">
+<span>{{ .Summary.Total }} items</span>
+<span class="passed">{{ .Summary.Passed }} passed</span>
+<span class="failed">{{ .Summary.Failed }} failed</span>
+<span>{{ .Summary.Skipped }} skipped</span>
+</div>
+<table>
+<tr><th>Collection</th><th>Item</th><th>Result</th><th>Status</th><th>Time</th><th>Attempts</th></tr>
+{{ range .Report.Results }}
+<tr>
+<td>{{ .Collection }}</td>
+<td>{{ .Path }}{{ if not .Passed }}<details><summary>details</summary><pre>{{ dump . }}</pre></details>{{ end }}</td>
+<td>{{ if .Skipped }}skipped{{ else if .Passed }}<span class="passed">passed</span>{{ else }}<span class="failed">failed</span>{{ end }}</td>
+<td>{{ if .Response }}{{ .Response.Status }}{{ end }}</td>
+<td>{{ ms .Duration }}</td>
+<td>{{ .Attempts }}</td>
+</tr>
+{{ end }}
+</table>
+</body>
+</html>
+`))
+
+// Report writes the report to r.Path
+func (r *HTMLReporter) Report(report *Report) error {
+	buf := &bytes.Buffer{}
+
+	data := struct {
+		Report  *Report
+		Summary Summary
+	}{report, report.Summary()}
+
+	if err := htmlReport.Execute(buf, data); err != nil {
+		return errors.Wrap(err, "failed to render HTML report")
+	}
+
+	return ioutil.WriteFile(r.Path, buf.Bytes(), 0644)
+}
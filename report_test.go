@@ -0,0 +1,172 @@
+package gopherman
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+// reportFunc is a Reporter that calls itself
+type reportFunc func(report *Report) error
+
+func (f reportFunc) Report(report *Report) error {
+	return f(report)
+}
+
+func testReport() *Report {
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return &Report{
+		Start: start,
+		End:   start.Add(3 * time.Second),
+		Results: []Result{
+			{Collection: "users", Path: "create", Start: start, Duration: time.Second, Attempts: 1},
+			{
+				Collection: "users",
+				Path:       "folder/get",
+				Start:      start,
+				Duration:   time.Second,
+				Attempts:   2,
+				Errors:     []string{"expected status 200, got 404\nmore detail"},
+				Request:    &RequestDump{Method: "GET", URL: "http://localhost/users/1", Header: http.Header{}},
+				Response:   &postman.Response{Status: 404, Raw: "not found"},
+			},
+			{Collection: "docs", Path: "list", Start: start, Skipped: true},
+		},
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "junit.xml")
+	assert.NoError(t, (&JUnitReporter{Path: path}).Report(testReport()))
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suites := junitSuites{}
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Skipped)
+	assert.Equal(t, float64(3), suites.Time)
+
+	if assert.Len(t, suites.Suites, 2) {
+		users, docs := suites.Suites[0], suites.Suites[1]
+
+		assert.Equal(t, "users", users.Name)
+		assert.Equal(t, 2, users.Tests)
+		assert.Equal(t, 1, users.Failures)
+		assert.Equal(t, "2018-01-02T03:04:05", users.Timestamp)
+		assert.Equal(t, float64(2), users.Time)
+
+		if assert.Len(t, users.Cases, 2) {
+			assert.Nil(t, users.Cases[0].Failure)
+			assert.Equal(t, "folder/get", users.Cases[1].Name)
+			assert.Equal(t, "users", users.Cases[1].Classname)
+
+			if assert.NotNil(t, users.Cases[1].Failure) {
+				assert.Equal(t, "expected status 200, got 404", users.Cases[1].Failure.Message)
+				assert.Contains(t, users.Cases[1].Failure.Text, "--- request ---\nGET http://localhost/users/1")
+				assert.Contains(t, users.Cases[1].Failure.Text, "--- response ---\nstatus 404")
+			}
+		}
+
+		assert.Equal(t, "docs", docs.Name)
+		if assert.Len(t, docs.Cases, 1) && assert.NotNil(t, docs.Cases[0].Skipped) {
+			assert.Equal(t, "no example responses", docs.Cases[0].Skipped.Message)
+		}
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "report.json")
+	assert.NoError(t, (&JSONReporter{Path: path}).Report(testReport()))
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := newmanReport{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "users, docs", report.Collection.Info.Name)
+	assert.Equal(t, newmanStat{Total: 1}, report.Run.Stats.Requests)
+	assert.Equal(t, newmanStat{Total: 3, Pending: 1, Failed: 1}, report.Run.Stats.Assertions)
+	assert.Len(t, report.Run.Executions, 3)
+
+	if assert.Len(t, report.Run.Failures, 1) {
+		assert.Equal(t, "users/folder/get", report.Run.Failures[0].At)
+	}
+}
+
+func TestReportsWrittenOncePerRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	dir, file := writeCollection(t, keyedItem("/a"), keyedItem("/b"))
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithServer(srv, dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tester.Vars.SetGlobal("apiKey", "key")
+
+	reports := []*Report{}
+	tester.Reporters = []Reporter{reportFunc(func(report *Report) error {
+		reports = append(reports, report)
+		return nil
+	})}
+
+	tester.Run(t)
+	tester.Run(t)
+
+	if assert.Len(t, reports, 2) {
+		// each run's report only has that run's results
+		assert.Len(t, reports[0].Results, 2)
+		assert.Len(t, reports[1].Results, 2)
+		assert.False(t, reports[1].Start.Before(reports[0].End))
+	}
+
+	reports = nil
+
+	tester.RunIterations(t, &postman.Data{Fields: []string{"id"}, Rows: []map[string]string{{"id": "1"}, {"id": "2"}, {"id": "3"}}})
+
+	if assert.Len(t, reports, 1) {
+		assert.Len(t, reports[0].Results, 6)
+		assert.Equal(t, 6, reports[0].Summary().Passed)
+	}
+}
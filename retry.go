@@ -0,0 +1,278 @@
+package gopherman
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// Readiness is a check that must pass before a run sends its first request, for example
+// while the service under test is still starting up. Exactly one of URL and Item should be set
+type Readiness struct {
+	// URL is polled until it responds with a status below 500. A relative URL is resolved against the target
+	URL string
+	// Item is the name of a collection item whose request is sent until it responds with its example's status
+	Item string
+
+	// Interval is the wait after the first failed check, doubling up to MaxInterval. Defaults to 100ms and 2s
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// Timeout is how long to wait in total before giving up. Defaults to 30s
+	Timeout time.Duration
+}
+
+// WaitReady waits until t.Readiness passes, the readiness timeout expires or ctx is done.
+// Once readiness has passed it isn't checked again, so runs after the first start immediately
+func (t *Tester) WaitReady(ctx context.Context) error {
+	if t.Readiness == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	ready := t.ready
+	t.mu.Unlock()
+
+	if ready {
+		return nil
+	}
+
+	client, err := t.client()
+	if err != nil {
+		return err
+	}
+
+	if err := t.waitReady(ctx, readyTarget{name: "target", point: t.setTarget, client: client}); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.ready = true
+	t.mu.Unlock()
+
+	return nil
+}
+
+// readyTarget is a server that readiness is checked against
+type readyTarget struct {
+	name string
+	// point aims a request at the server
+	point  func(req *http.Request, scope *postman.Scope) error
+	client *http.Client
+}
+
+// waitReady runs t.Readiness against target until it passes, the readiness timeout expires or ctx is done
+func (t *Tester) waitReady(ctx context.Context, target readyTarget) error {
+	r := t.Readiness
+
+	interval, maxInterval, timeout := r.Interval, r.MaxInterval, r.Timeout
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+
+	if maxInterval <= 0 {
+		maxInterval = 2 * time.Second
+	}
+
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := t.checkReady(ctx, target)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(err, "%s not ready after %d attempts (%s)", target.name, attempt, time.Since(start).Round(time.Millisecond))
+		case <-time.After(interval):
+		}
+
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// checkReady runs the readiness check against target once
+func (t *Tester) checkReady(ctx context.Context, target readyTarget) error {
+	if t.Readiness.Item != "" {
+		return t.checkItemReady(ctx, target, t.Readiness.Item)
+	}
+
+	if t.Readiness.URL == "" {
+		return errors.New("readiness needs a URL or an Item")
+	}
+
+	check, err := url.Parse(t.Readiness.URL)
+	if err != nil {
+		return errors.Wrap(err, "invalid readiness URL")
+	}
+
+	req := &http.Request{Method: http.MethodGet, URL: check, Header: http.Header{}}
+
+	if !check.IsAbs() {
+		if err := target.point(req, t.Vars); err != nil {
+			return err
+		}
+	}
+
+	resp, _, err := makeRequest(target.client, req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	if resp.Status >= 500 {
+		return fmt.Errorf("readiness check %s returned status %d", check, resp.Status)
+	}
+
+	return nil
+}
+
+func (t *Tester) checkItemReady(ctx context.Context, target readyTarget, name string) error {
+	for i := range t.Collections {
+		collection := &t.Collections[i]
+
+		itm := collection.ItemWithName(name)
+		if itm == nil {
+			continue
+		}
+
+		scope, err := t.scopeFor(collection)
+		if err != nil {
+			return err
+		}
+
+		httpReq, err := itm.Request.HTTPRequestWithScope(scope)
+		if err != nil {
+			return err
+		}
+
+		if err := target.point(httpReq, scope); err != nil {
+			return err
+		}
+
+		resp, _, err := makeRequest(target.client, httpReq.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+
+		if len(itm.Response) > 0 && itm.Response[0].Status != 0 {
+			if resp.Status != itm.Response[0].Status {
+				return fmt.Errorf("readiness item %s returned status %d, expected %d", name, resp.Status, itm.Response[0].Status)
+			}
+		} else if resp.Status >= 500 {
+			return fmt.Errorf("readiness item %s returned status %d", name, resp.Status)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("readiness item %s doesn't exist", name)
+}
+
+// Retry sets the retry policy for the named item, overriding any annotated in the collection
+func (t *Tester) Retry(name string, policy postman.RetryPolicy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.retries == nil {
+		t.retries = map[string]*postman.RetryPolicy{}
+	}
+
+	t.retries[name] = &policy
+}
+
+func (t *Tester) retryPolicyFor(itm *postman.CollectionItem) *postman.RetryPolicy {
+	t.mu.Lock()
+	policy, ok := t.retries[itm.Name]
+	t.mu.Unlock()
+
+	if ok {
+		return policy
+	}
+
+	if itm.Gopherman != nil && itm.Gopherman.Retry != nil {
+		return itm.Gopherman.Retry
+	}
+
+	return t.DefaultRetry
+}
+
+// send sends req, retrying according to policy. Each attempt gets its own t.Timeout.
+// Retries are logged and counted in helper.Attempts
+func (t *Tester) send(ctx context.Context, client *http.Client, req *http.Request, policy *postman.RetryPolicy, helper *TestHelper) (*postman.Response, http.Header, error) {
+	retries, backoff := 0, time.Duration(0)
+	if policy != nil {
+		retries = policy.Retries
+		backoff = time.Duration(policy.BackoffMS) * time.Millisecond
+	}
+
+	for attempt := 1; ; attempt++ {
+		helper.Attempts = attempt
+
+		resp, header, err := t.attempt(ctx, client, req)
+
+		var reason string
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// the run is over, so retrying can't help
+			return nil, nil, err
+		case err != nil && policy != nil && policy.ConnectionErrors:
+			reason = err.Error()
+		case err == nil && policy != nil && policy.RetriesStatus(resp.Status):
+			reason = fmt.Sprintf("status %d", resp.Status)
+		}
+
+		if reason == "" || attempt > retries {
+			if err != nil && attempt > 1 {
+				err = errors.Wrapf(err, "failed after %d attempts", attempt)
+			}
+
+			return resp, header, err
+		}
+
+		helper.Log(fmt.Sprintf("retrying %s %s after %s (attempt %d of %d)", req.Method, req.URL, reason, attempt+1, retries+1))
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// attempt sends a single copy of req
+func (t *Tester) attempt(ctx context.Context, client *http.Client, req *http.Request) (*postman.Response, http.Header, error) {
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	attemptReq := req.Clone(ctx)
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to GetBody")
+		}
+
+		attemptReq.Body = body
+	}
+
+	return makeRequest(client, attemptReq)
+}
@@ -0,0 +1,143 @@
+package gopherman
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyServer responds with 503 to its first failures requests and 200 after that, counting requests
+type flakyServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	requests int
+}
+
+func newFlakyServer(failures int) *flakyServer {
+	f := &flakyServer{failures: failures}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests++
+		failing := f.requests <= f.failures
+		f.mu.Unlock()
+
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	return f
+}
+
+func (f *flakyServer) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests
+}
+
+func TestWaitReady(t *testing.T) {
+	srv := newFlakyServer(2)
+	defer srv.Close()
+
+	tester := &Tester{
+		Vars:      postman.NewScope(),
+		Client:    srv.Client(),
+		Target:    &Target{BaseURL: srv.URL},
+		Readiness: &Readiness{URL: "/health", Interval: time.Millisecond},
+	}
+
+	assert.NoError(t, tester.WaitReady(context.Background()))
+	assert.Equal(t, 3, srv.count())
+
+	// readiness is only checked once
+	assert.NoError(t, tester.WaitReady(context.Background()))
+	assert.Equal(t, 3, srv.count())
+}
+
+func TestWaitReadyTimeout(t *testing.T) {
+	srv := newFlakyServer(1000)
+	defer srv.Close()
+
+	tester := &Tester{
+		Vars:      postman.NewScope(),
+		Client:    srv.Client(),
+		Target:    &Target{BaseURL: srv.URL},
+		Readiness: &Readiness{URL: "/health", Interval: time.Millisecond, MaxInterval: 5 * time.Millisecond, Timeout: 50 * time.Millisecond},
+	}
+
+	err := tester.WaitReady(context.Background())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "target not ready after")
+	}
+
+	assert.True(t, srv.count() > 1)
+}
+
+func TestSendRetries(t *testing.T) {
+	policy := &postman.RetryPolicy{Retries: 2, Statuses: []int{http.StatusServiceUnavailable}, BackoffMS: 1}
+
+	tests := []struct {
+		name     string
+		failures int
+		policy   *postman.RetryPolicy
+		status   int
+		attempts int
+	}{
+		{"no policy", 1, nil, http.StatusServiceUnavailable, 1},
+		{"succeeds on a retry", 2, policy, http.StatusOK, 3},
+		{"retries run out", 3, policy, http.StatusServiceUnavailable, 3},
+		{"other statuses aren't retried", 1, &postman.RetryPolicy{Retries: 2, Statuses: []int{http.StatusBadGateway}}, http.StatusServiceUnavailable, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := newFlakyServer(test.failures)
+			defer srv.Close()
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/things", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			helper := NewTestHelper(t)
+
+			resp, _, err := (&Tester{}).send(context.Background(), srv.Client(), req, test.policy, helper)
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.status, resp.Status)
+			}
+
+			assert.Equal(t, test.attempts, helper.Attempts)
+			assert.Equal(t, test.attempts, srv.count())
+		})
+	}
+}
+
+func TestSendRetriesConnectionErrors(t *testing.T) {
+	srv := newFlakyServer(0)
+	url := srv.URL
+	srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helper := NewTestHelper(t)
+
+	_, _, err = (&Tester{}).send(context.Background(), http.DefaultClient, req, &postman.RetryPolicy{Retries: 1, ConnectionErrors: true}, helper)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed after 2 attempts")
+	}
+
+	assert.Equal(t, 2, helper.Attempts)
+}
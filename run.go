@@ -0,0 +1,217 @@
+package gopherman
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+)
+
+// Handler checks the actual response to a request against the expected example
+type Handler func(helper *TestHelper, req *postman.Request, expected *postman.Response, actual *postman.Response)
+
+// Run runs every item in every collection as a subtest, in collection order.
+// Subtests are named after the collection, then each folder, then the item, and
+// compare responses using t.Handler, or DefaultHandler if it's nil
+func (t *Tester) Run(tst *testing.T) {
+	t.RunContext(context.Background(), tst)
+}
+
+// RunContext is like Run, but stops sending requests once ctx is done.
+// If t.RunTimeout is set the whole run must finish within it. Each run starts
+// with a new cookie jar, seeded with t.Cookies.
+//
+// If t.Parallel is set, items marked independent run in parallel with each other,
+// at most t.Parallel at a time, after the other items in the same folder have run in order.
+// Items that chain values to or from other items must not be marked independent.
+//
+// The report starts afresh, and is given to t.Reporters once the run has finished
+func (t *Tester) RunContext(ctx context.Context, tst *testing.T) {
+	t.resetReport()
+	t.runCollections(ctx, tst)
+	t.finishRun(tst)
+}
+
+// runCollections runs every collection as a subtest of tst, without resetting or writing the report
+func (t *Tester) runCollections(ctx context.Context, tst *testing.T) {
+	if t.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.RunTimeout)
+		defer cancel()
+	}
+
+	if err := t.ResetCookies(); err != nil {
+		tst.Fatal(err)
+	}
+
+	if err := t.WaitReady(ctx); err != nil {
+		tst.Fatal(err)
+	}
+
+	r := &run{ctx: ctx}
+	if t.Parallel > 0 {
+		r.sem = make(chan struct{}, t.Parallel)
+	}
+
+	for i := range t.Collections {
+		collection := &t.Collections[i]
+
+		// parallel subtests finish before tst.Run returns, so ctx outlives them
+		tst.Run(collection.Info.Name, func(ct *testing.T) {
+			t.runItems(r, ct, collection, nil, collection.Item)
+		})
+	}
+}
+
+// finishRun writes the examples recorded in update mode and the reports at the end of a run
+func (t *Tester) finishRun(tst *testing.T) {
+	if err := t.WriteUpdates(); err != nil {
+		tst.Error(err)
+	}
+
+	if err := t.WriteReports(); err != nil {
+		tst.Error(err)
+	}
+}
+
+// run holds the state shared by every item in a single run
+type run struct {
+	ctx context.Context
+	// sem limits how many independent items run at once, nil if they run in order
+	sem chan struct{}
+}
+
+// runItems runs items as subtests of tst, descending into folders
+func (t *Tester) runItems(r *run, tst *testing.T, collection *postman.Collection, folders []string, items []postman.CollectionItem) {
+	for i := range items {
+		itm := &items[i]
+		path := append(append([]string{}, folders...), itm.Name)
+
+		tst.Run(itm.Name, func(it *testing.T) {
+			if itm.IsFolder() {
+				t.runItems(r, it, collection, path, itm.Item)
+				return
+			}
+
+			if r.sem != nil && t.isIndependent(itm) {
+				it.Parallel()
+
+				r.sem <- struct{}{}
+				defer func() { <-r.sem }()
+			}
+
+			helper := t.newHelper(it)
+			if t.runItem(r.ctx, helper, collection, strings.Join(path, "/"), itm, firstExample, t.handler()) {
+				it.Skip("item has no example responses")
+			}
+
+			AssertErrors(it, helper.AnnotateErrors(collection.Info.Name, strings.Join(path, "/")))
+		})
+	}
+}
+
+// Independent marks the named items as safe to run in parallel, in addition to any
+// annotated as independent in the collection. See RunContext
+func (t *Tester) Independent(names ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.independent == nil {
+		t.independent = map[string]bool{}
+	}
+
+	for _, name := range names {
+		t.independent[name] = true
+	}
+}
+
+func (t *Tester) isIndependent(itm *postman.CollectionItem) bool {
+	if itm.Gopherman != nil && itm.Gopherman.Independent {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.independent[itm.Name]
+}
+
+func (t *Tester) handler() Handler {
+	if t.Handler != nil {
+		return t.Handler
+	}
+
+	return DefaultHandler
+}
+
+// DefaultHandler checks that the actual status, headers and body match the expected example,
+// comparing bodies with the helper's Comparator. Bodies of items with a JSON Schema
+// have already been validated against it, so they aren't compared with the example
+func DefaultHandler(helper *TestHelper, req *postman.Request, expected *postman.Response, actual *postman.Response) {
+	if expected.Status != 0 && expected.Status != actual.Status {
+		helper.Error(fmt.Errorf("expected status %d, got %d", expected.Status, actual.Status))
+	}
+
+	helper.CompareHeaders(expected, actual)
+
+	if helper.Schema == nil {
+		helper.CompareBody(expected, actual)
+	}
+}
+
+// RunIterations runs every item in every collection once per row of data, like Run.
+// Each row is its own subtest, named after its index and the values of keyFields
+// (the first field if none are given), with the row's values in the data scope.
+// Runtime variables and cookies are reset at the start of each iteration. Every
+// iteration's results go in one report, written once the last iteration has run
+func (t *Tester) RunIterations(tst *testing.T, data *postman.Data, keyFields ...string) {
+	t.resetReport()
+
+	t.iterate(tst, data, keyFields, func(it *testing.T) {
+		t.runCollections(context.Background(), it)
+	})
+
+	t.finishRun(tst)
+}
+
+// RunItemIterations runs the named item once per row of data, comparing responses with
+// t.Handler or DefaultHandler. See RunIterations
+func (t *Tester) RunItemIterations(name string, tst *testing.T, data *postman.Data, keyFields ...string) {
+	t.iterate(tst, data, keyFields, func(it *testing.T) {
+		AssertErrors(it, t.TestRequestWithName(name, it, t.handler()))
+	})
+}
+
+func (t *Tester) iterate(tst *testing.T, data *postman.Data, keyFields []string, fn func(*testing.T)) {
+	if len(keyFields) == 0 && len(data.Fields) > 0 {
+		keyFields = data.Fields[:1]
+	}
+
+	defer func() {
+		t.Vars.Data = map[string]string{}
+	}()
+
+	for i, row := range data.Rows {
+		t.Vars.Data = row
+		t.Vars.Local = map[string]string{}
+
+		if err := t.ResetCookies(); err != nil {
+			tst.Fatal(err)
+		}
+
+		tst.Run(iterationName(i, row, keyFields), fn)
+	}
+}
+
+// iterationName names an iteration subtest, e.g. "iteration 3 id=42"
+func iterationName(index int, row map[string]string, keyFields []string) string {
+	name := fmt.Sprintf("iteration %d", index)
+
+	for _, field := range keyFields {
+		name += fmt.Sprintf(" %s=%s", field, row[field])
+	}
+
+	return name
+}
@@ -0,0 +1,171 @@
+package gopherman
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+// keyedItem returns an item that sends the apiKey variable to path and expects 200
+func keyedItem(path string) postman.CollectionItem {
+	return postman.CollectionItem{
+		Name: path,
+		Request: postman.Request{
+			Method: http.MethodGet,
+			Header: []postman.Header{{Key: "X-Key", Value: "{{apiKey}}"}},
+			URL:    postman.URL{Raw: "http://localhost" + path},
+		},
+		Response:  []postman.Response{{Status: http.StatusOK, Raw: `{"ok":true}`}},
+		Gopherman: &postman.Annotation{Independent: true},
+	}
+}
+
+func TestRunWalksFolders(t *testing.T) {
+	mu := sync.Mutex{}
+	paths := []string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	named := func(name string) postman.CollectionItem {
+		itm := keyedItem("/" + name)
+		itm.Name = name
+		return itm
+	}
+
+	folder := postman.CollectionItem{Name: "folder", Item: []postman.CollectionItem{
+		named("b"),
+		{Name: "nested", Item: []postman.CollectionItem{named("c")}},
+	}}
+
+	dir, file := writeCollection(t, named("a"), folder, named("d"))
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithServer(srv, dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tester.Vars.SetGlobal("apiKey", "key")
+
+	tester.Run(t)
+
+	assert.Equal(t, []string{"/a", "/b", "/c", "/d"}, paths)
+
+	results := []string{}
+	for _, res := range tester.Report().Results {
+		assert.True(t, res.Passed(), res.Path)
+		results = append(results, res.Collection+" "+res.Path)
+	}
+
+	assert.Equal(t, []string{"test a", "test folder/b", "test folder/nested/c", "test d"}, results)
+}
+
+func TestDefaultHandler(t *testing.T) {
+	expected := &postman.Response{Status: 200, Raw: `{"id":1,"name":"a"}`}
+
+	tests := []struct {
+		name   string
+		actual *postman.Response
+		errs   int
+	}{
+		{"matches", &postman.Response{Status: 200, Raw: `{"name":"a","id":1}`}, 0},
+		{"status", &postman.Response{Status: 500, Raw: `{"id":1,"name":"a"}`}, 1},
+		{"body", &postman.Response{Status: 200, Raw: `{"id":2,"name":"a"}`}, 1},
+		{"both", &postman.Response{Status: 404, Raw: `{}`}, 2},
+	}
+
+	for _, test := range tests {
+		helper := NewTestHelper(t)
+		DefaultHandler(helper, &postman.Request{}, expected, test.actual)
+
+		assert.Equal(t, test.errs > 0, helper.HasErrors(), test.name)
+		assert.Len(t, helper.errors, test.errs, test.name)
+	}
+}
+
+func TestRunParallelWithSecrets(t *testing.T) {
+	mu := sync.Mutex{}
+	seen := map[string]string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = r.Header.Get("X-Key")
+		mu.Unlock()
+
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	items := []postman.CollectionItem{}
+	for i := 0; i < 4; i++ {
+		items = append(items, keyedItem(fmt.Sprintf("/items/%d", i)))
+	}
+
+	collection := postman.NewCollection("parallel", items, nil)
+	collection.Variable = []postman.Variable{{Key: "apiKey", Value: "s3cret", Type: postman.SecretType}}
+
+	dir, file := writeCollection(t)
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithServer(srv, dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tester.Collections = []postman.Collection{*collection}
+	tester.Parallel = 4
+
+	tester.Run(t)
+
+	assert.Equal(t, map[string]string{"/items/0": "s3cret", "/items/1": "s3cret", "/items/2": "s3cret", "/items/3": "s3cret"}, seen)
+	assert.True(t, tester.Secrets.IsSecret("s3cret"))
+}
+
+func TestRunItemIterations(t *testing.T) {
+	paths := []string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	itm := keyedItem("/users/{{id}}")
+	itm.Name = "user"
+
+	dir, file := writeCollection(t, itm)
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithServer(srv, dir, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := &postman.Data{Fields: []string{"id", "apiKey"}, Rows: []map[string]string{{"id": "1", "apiKey": "a"}, {"id": "2", "apiKey": "b"}}}
+
+	tester.RunItemIterations("user", t, data)
+
+	assert.Equal(t, []string{"/users/1", "/users/2"}, paths)
+	assert.Empty(t, tester.Vars.Data, "the data scope is cleared afterwards")
+}
+
+func TestIterationName(t *testing.T) {
+	row := map[string]string{"id": "42", "name": "alice"}
+
+	assert.Equal(t, "iteration 3", iterationName(3, row, nil))
+	assert.Equal(t, "iteration 3 id=42", iterationName(3, row, []string{"id"}))
+	assert.Equal(t, "iteration 0 name=alice id=42", iterationName(0, row, []string{"name", "id"}))
+}
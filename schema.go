@@ -0,0 +1,108 @@
+package gopherman
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cohix/gopherman/jsonschema"
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// Schema sets the JSON Schema that the named item's response bodies must conform to,
+// overriding any annotated in the collection
+func (t *Tester) Schema(name string, schema *jsonschema.Schema) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.schemas == nil {
+		t.schemas = map[string]*jsonschema.Schema{}
+	}
+
+	t.schemas[name] = schema
+}
+
+func (t *Tester) hasSchema(itm *postman.CollectionItem) bool {
+	t.mu.Lock()
+	_, ok := t.schemas[itm.Name]
+	t.mu.Unlock()
+
+	return ok || itm.HasSchema()
+}
+
+// schemaFor returns the item's schema, from Schema or its annotation, or nil if it has none.
+// Schema files are found relative to the collection's file
+func (t *Tester) schemaFor(collection *postman.Collection, itm *postman.CollectionItem) (*jsonschema.Schema, error) {
+	t.mu.Lock()
+	schema, ok := t.schemas[itm.Name]
+	t.mu.Unlock()
+
+	if ok {
+		return schema, nil
+	}
+
+	if itm.Gopherman == nil {
+		return nil, nil
+	}
+
+	if len(itm.Gopherman.Schema) > 0 {
+		schema, err := jsonschema.Parse(itm.Gopherman.Schema)
+		return schema, errors.Wrapf(err, "item with name %s has an invalid schema", itm.Name)
+	}
+
+	if itm.Gopherman.SchemaFile == "" {
+		return nil, nil
+	}
+
+	path := itm.Gopherman.SchemaFile
+	if !filepath.IsAbs(path) {
+		dir := ""
+		for i := range t.Collections {
+			if &t.Collections[i] == collection && i < len(t.files) {
+				dir = filepath.Dir(t.files[i])
+			}
+		}
+
+		path = filepath.Join(dir, path)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if schema, ok := t.schemaFiles[path]; ok {
+		return schema, nil
+	}
+
+	schema, err := jsonschema.ParseFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "item with name %s has an invalid schema file", itm.Name)
+	}
+
+	if t.schemaFiles == nil {
+		t.schemaFiles = map[string]*jsonschema.Schema{}
+	}
+
+	t.schemaFiles[path] = schema
+
+	return schema, nil
+}
+
+// validateSchema validates the actual response body against the item's schema, if it has one,
+// adding an error to helper for each violation
+func (t *Tester) validateSchema(helper *TestHelper, collection *postman.Collection, itm *postman.CollectionItem, actual *postman.Response) {
+	schema, err := t.schemaFor(collection, itm)
+	if err != nil {
+		helper.Error(err)
+		return
+	}
+
+	if schema == nil {
+		return
+	}
+
+	helper.Schema = schema
+
+	for _, violation := range schema.Validate([]byte(actual.Raw)) {
+		helper.Error(fmt.Errorf("response doesn't match schema: %s", violation))
+	}
+}
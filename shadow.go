@@ -0,0 +1,348 @@
+package gopherman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// ShadowServer is one of the two servers a shadow replay sends every request to
+type ShadowServer struct {
+	Name string
+
+	// Target is where the server is. Its BaseURL or Socket is required
+	Target Target
+	// Client sends the server's requests, defaulting to the tester's Client
+	Client *http.Client
+}
+
+// ShadowURL returns a shadow server at baseURL
+func ShadowURL(name, baseURL string) ShadowServer {
+	return ShadowServer{Name: name, Target: Target{BaseURL: baseURL}}
+}
+
+// ShadowTestServer returns a shadow server for srv, using its own client so TLS servers work
+func ShadowTestServer(name string, srv *httptest.Server) ShadowServer {
+	return ShadowServer{Name: name, Target: Target{BaseURL: srv.URL}, Client: srv.Client()}
+}
+
+// ShadowHandler returns a shadow server that serves requests with handler in-process
+func ShadowHandler(name string, handler http.Handler) ShadowServer {
+	return ShadowServer{
+		Name:   name,
+		Target: Target{BaseURL: inProcessURL},
+		Client: &http.Client{Transport: &handlerTransport{handler: handler}},
+	}
+}
+
+// ShadowOptions configures a shadow replay
+type ShadowOptions struct {
+	// Items are the names of the items to replay, every item if empty
+	Items []string
+
+	// Comparator compares the response bodies, defaulting to the tester's Comparator.
+	// Its ignore rules and matchers leave out values that are expected to differ, such as generated IDs
+	Comparator *Comparator
+
+	// IgnoreHeaders are left out when comparing response headers, along with VolatileHeaders.
+	// Headers are only compared if CompareHeaders is set
+	IgnoreHeaders  []string
+	CompareHeaders bool
+}
+
+// ShadowDiff is an item whose responses from the two servers differ. Differences compare the
+// candidate's response with the base's, with paths "status", "error", "header" or a JSON path into the body
+type ShadowDiff struct {
+	Collection  string
+	Item        string
+	Method      string
+	Base        *postman.Response `json:",omitempty"`
+	Candidate   *postman.Response `json:",omitempty"`
+	Differences []Difference
+}
+
+// ShadowReport is the result of a shadow replay
+type ShadowReport struct {
+	Base      string
+	Candidate string
+	Items     int
+	Diffs     []ShadowDiff
+}
+
+// Shadow sends each item's request to both base and candidate, and reports every item whose responses
+// differ in status, body or, optionally, headers. Bodies are compared with the options' Comparator,
+// with the base's response as the expected one.
+//
+// Each server has its own runtime variables and cookie jar, so values extracted from one server's
+// responses, such as the IDs of created resources, are only sent back to that server. If t.Readiness
+// is set, both servers must pass it first. Secret values are masked in the report's responses and differences
+func (t *Tester) Shadow(ctx context.Context, base, candidate ShadowServer, opts ShadowOptions) (*ShadowReport, error) {
+	comparator := opts.Comparator
+	if comparator == nil {
+		comparator = t.Comparator
+	}
+
+	if comparator == nil {
+		comparator = NewComparator()
+	}
+
+	sides := []*shadowSide{}
+	for _, srv := range []ShadowServer{base, candidate} {
+		side, err := t.newShadowSide(srv)
+		if err != nil {
+			return nil, err
+		}
+
+		sides = append(sides, side)
+	}
+
+	if t.Readiness != nil {
+		for _, side := range sides {
+			target := readyTarget{name: "shadow server " + side.server.Name, point: side.point, client: side.client}
+			if err := t.waitReady(ctx, target); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	report := &ShadowReport{Base: base.Name, Candidate: candidate.Name}
+
+	for i := range t.Collections {
+		collection := &t.Collections[i]
+
+		for _, side := range sides {
+			scope, err := t.scopeFor(collection)
+			if err != nil {
+				return report, err
+			}
+
+			side.scope = scope.WithLocal(t.Vars.LocalCopy())
+		}
+
+		var err error
+		collection.Walk(func(folders []string, itm *postman.CollectionItem) {
+			if err != nil || ctx.Err() != nil || !selectedItem(opts.Items, itm) {
+				return
+			}
+
+			report.Items++
+
+			diff := ShadowDiff{
+				Collection: collection.Info.Name,
+				Item:       strings.Join(append(append([]string{}, folders...), itm.Name), "/"),
+				Method:     strings.ToUpper(itm.Request.Method),
+			}
+
+			responses := make([]*postman.Response, len(sides))
+			for s, side := range sides {
+				resp, sendErr := side.send(ctx, t, itm)
+				if sendErr != nil {
+					if _, ok := sendErr.(*shadowSetupError); ok {
+						err = sendErr
+						return
+					}
+
+					diff.Differences = append(diff.Differences, Difference{
+						Path:    "error",
+						Message: fmt.Sprintf("%s failed: %s", side.server.Name, sendErr),
+					})
+				}
+
+				responses[s] = resp
+			}
+
+			diff.Differences = append(diff.Differences, compareShadow(comparator, opts, responses[0], responses[1])...)
+
+			if len(diff.Differences) > 0 {
+				report.Diffs = append(report.Diffs, t.maskShadowDiff(diff, responses[0], responses[1]))
+			}
+		})
+
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, ctx.Err()
+}
+
+// RunShadow runs Shadow and fails tst with an error for each item whose responses differ
+func (t *Tester) RunShadow(tst *testing.T, base, candidate ShadowServer, opts ShadowOptions) *ShadowReport {
+	tst.Helper()
+
+	report, err := t.Shadow(context.Background(), base, candidate, opts)
+	if err != nil {
+		tst.Fatal(err)
+	}
+
+	for _, diff := range report.Diffs {
+		tst.Errorf("%s/%s differs between %s and %s:\n%s", diff.Collection, diff.Item, report.Base, report.Candidate,
+			FormatDifferences(diff.Differences))
+	}
+
+	return report
+}
+
+// maskShadowDiff sets the diff's responses and masks secret values in them and its differences,
+// so that the report can be printed and written out as it is
+func (t *Tester) maskShadowDiff(diff ShadowDiff, base, candidate *postman.Response) ShadowDiff {
+	diff.Base = t.Secrets.MaskResponse(base)
+	diff.Candidate = t.Secrets.MaskResponse(candidate)
+
+	for i := range diff.Differences {
+		diff.Differences[i].Message = t.Secrets.Mask(diff.Differences[i].Message)
+	}
+
+	return diff
+}
+
+// compareShadow returns the differences between the base and candidate responses, if both were received
+func compareShadow(comparator *Comparator, opts ShadowOptions, base, candidate *postman.Response) []Difference {
+	if base == nil || candidate == nil {
+		return nil
+	}
+
+	diffs := []Difference{}
+
+	if base.Status != candidate.Status {
+		diffs = append(diffs, Difference{
+			Path:    "status",
+			Message: fmt.Sprintf("expected %d, got %d", base.Status, candidate.Status),
+		})
+	}
+
+	if opts.CompareHeaders {
+		for _, err := range headerErrors(base, candidate, opts.IgnoreHeaders) {
+			diffs = append(diffs, Difference{Path: "header", Message: err.Error()})
+		}
+	}
+
+	return append(diffs, comparator.Compare([]byte(base.Raw), []byte(candidate.Raw))...)
+}
+
+// shadowSetupError is a misconfigured shadow server, which is a problem with the replay rather than a server
+type shadowSetupError struct {
+	err error
+}
+
+func (e *shadowSetupError) Error() string {
+	return e.err.Error()
+}
+
+// shadowSide sends requests to one of the servers of a shadow replay
+type shadowSide struct {
+	server ShadowServer
+	client *http.Client
+	scope  *postman.Scope
+}
+
+func (t *Tester) newShadowSide(srv ShadowServer) (*shadowSide, error) {
+	if srv.Target.BaseURL == "" && srv.Target.Socket == "" {
+		return nil, fmt.Errorf("shadow server %s has no BaseURL or Socket", srv.Name)
+	}
+
+	client := srv.Client
+	if client == nil {
+		client = t.Client
+	}
+
+	transport, err := srv.Target.transport()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid target for shadow server %s", srv.Name)
+	}
+
+	withJar := *client
+	if transport != nil {
+		withJar.Transport = transport
+	}
+
+	if withJar.Jar == nil {
+		jar, err := newCookieJar(t.Cookies)
+		if err != nil {
+			return nil, err
+		}
+
+		withJar.Jar = jar
+	}
+
+	return &shadowSide{server: srv, client: &withJar}, nil
+}
+
+// point aims req at the side's server
+func (s *shadowSide) point(req *http.Request, scope *postman.Scope) error {
+	raw := s.server.Target.BaseURL
+	if raw == "" {
+		raw = "http://localhost"
+	}
+
+	base, err := parseBaseURL(s.server.Name+" BaseURL", raw, scope.Map())
+	if err != nil {
+		return &shadowSetupError{err}
+	}
+
+	pointAt(req, base)
+
+	return nil
+}
+
+// send sends the item's request to the side's server and extracts values from the response into its scope
+func (s *shadowSide) send(ctx context.Context, t *Tester, itm *postman.CollectionItem) (*postman.Response, error) {
+	httpReq, err := itm.Request.HTTPRequestWithScope(s.scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.point(httpReq, s.scope); err != nil {
+		return nil, err
+	}
+
+	resp, header, err := t.attempt(ctx, s.client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// values that can't be extracted show up as differences in later items
+	extract(s.scope, t.extractionsFor(itm), resp, header)
+
+	return resp, nil
+}
+
+// String formats the report as each differing item followed by its differences
+func (r *ShadowReport) String() string {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "replayed %d item(s) against %s and %s, %d differ\n", r.Items, r.Base, r.Candidate, len(r.Diffs))
+
+	for _, diff := range r.Diffs {
+		fmt.Fprintf(buf, "\n%s %s/%s\n", diff.Method, diff.Collection, diff.Item)
+
+		for _, d := range diff.Differences {
+			fmt.Fprintf(buf, "  %s\n", d.Error())
+		}
+	}
+
+	return buf.String()
+}
+
+// WriteJSON writes the report as JSON to path
+func (r *ShadowReport) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal shadow report")
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write shadow report")
+	}
+
+	return nil
+}
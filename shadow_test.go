@@ -0,0 +1,168 @@
+package gopherman
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+// versionServer serves docs like authzCollection expects, numbering them from start. It answers its
+// health check with 503 until it has been asked unhealthy times, and counts requests made before then
+type versionServer struct {
+	version   int
+	start     int
+	unhealthy int
+
+	mu     sync.Mutex
+	checks int
+	early  int
+	docs   map[string]string
+}
+
+func (v *versionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r.URL.Path == "/health" {
+		v.checks++
+		if v.checks <= v.unhealthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		return
+	}
+
+	if v.checks <= v.unhealthy {
+		v.early++
+	}
+
+	if v.docs == nil {
+		v.docs = map[string]string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Version", fmt.Sprint(v.version))
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	if r.Method == http.MethodPost {
+		id := fmt.Sprint(v.start + len(v.docs) + 1)
+		v.docs[id] = token
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":"%s","owner":"%s"}`, id, token)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/docs/")
+	if _, ok := v.docs[id]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodDelete && v.version == 2:
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	case v.version == 2:
+		fmt.Fprintf(w, `{"id":"%s","owner":"%s","count":"1"}`, id, v.docs[id])
+	default:
+		fmt.Fprintf(w, `{"id":"%s","owner":"%s","count":1}`, id, v.docs[id])
+	}
+}
+
+func TestShadow(t *testing.T) {
+	base := &versionServer{version: 1}
+	candidate := &versionServer{version: 2, start: 100, unhealthy: 2}
+
+	baseSrv := httptest.NewServer(base)
+	defer baseSrv.Close()
+
+	candidateSrv := httptest.NewServer(candidate)
+	defer candidateSrv.Close()
+
+	dir := writeTempFile(t, "docs.json", []byte(authzCollection))
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithCollection(dir, "", "docs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &postman.Environment{Values: []postman.Variable{{Key: "token", Value: "s3cret", Type: postman.SecretType, Enabled: true}}}
+	if err := tester.UseEnvironment(env); err != nil {
+		t.Fatal(err)
+	}
+
+	tester.Readiness = &Readiness{URL: "/health", Interval: time.Millisecond, Timeout: 5 * time.Second}
+
+	opts := ShadowOptions{Comparator: NewComparator().Ignore("$.id"), CompareHeaders: true, IgnoreHeaders: []string{"X-Version"}}
+
+	report, err := tester.Shadow(context.Background(), ShadowTestServer("v1", baseSrv), ShadowTestServer("v2", candidateSrv), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 3, candidate.checks)
+	assert.Equal(t, 0, candidate.early)
+	assert.Equal(t, 1, base.checks)
+
+	// each server gets back the id it generated
+	assert.Equal(t, map[string]string{"1": "s3cret"}, base.docs)
+	assert.Equal(t, map[string]string{"101": "s3cret"}, candidate.docs)
+
+	assert.Equal(t, 3, report.Items)
+
+	differences := map[string]string{}
+	for _, diff := range report.Diffs {
+		differences[diff.Item] = FormatDifferences(diff.Differences)
+	}
+
+	assert.Equal(t, map[string]string{
+		"get":    `$.count: expected number 1, got string "1"`,
+		"delete": "status: expected 204, got 200",
+	}, differences)
+
+	path := filepath.Join(dir, "report.json")
+	if assert.NoError(t, report.WriteJSON(path)) {
+		data, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "s3cret")
+		assert.Contains(t, string(data), `"Raw": "{\"id\":\"1\",\"owner\":\"********\",\"count\":1}"`)
+	}
+}
+
+func TestShadowNotReady(t *testing.T) {
+	srv := httptest.NewServer(&versionServer{unhealthy: 1000})
+	defer srv.Close()
+
+	dir := writeTempFile(t, "docs.json", []byte(authzCollection))
+	defer os.RemoveAll(dir)
+
+	tester, err := NewTesterWithCollection(dir, "", "docs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tester.Readiness = &Readiness{URL: "/health", Interval: time.Millisecond, Timeout: 50 * time.Millisecond}
+
+	_, err = tester.Shadow(context.Background(), ShadowTestServer("v1", srv), ShadowURL("v2", "http://127.0.0.1:1"), ShadowOptions{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "shadow server v1 not ready")
+	}
+
+	_, err = tester.Shadow(context.Background(), ShadowTestServer("v1", srv), ShadowServer{Name: "v2"}, ShadowOptions{})
+	assert.EqualError(t, err, "shadow server v2 has no BaseURL or Socket")
+}
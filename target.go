@@ -0,0 +1,261 @@
+package gopherman
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/pkg/errors"
+)
+
+// Target describes where the server under test is and how to connect to it
+type Target struct {
+	// BaseURL is the scheme, host, port and optional path prefix requests are sent to,
+	// e.g. https://api.local:8443/v1. Variables are substituted
+	BaseURL string
+
+	// Socket is the path of a Unix domain socket to connect to instead of dialing BaseURL's host.
+	// BaseURL still supplies the scheme, Host header and path prefix, and defaults to http://localhost
+	Socket string
+
+	// TLS configures connections to https targets
+	TLS *TLSConfig
+}
+
+// TLSConfig configures TLS connections to a target
+type TLSConfig struct {
+	// CAFile is a PEM file of CA certificates to trust in addition to the system pool
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key, for mutual TLS
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name the server's certificate is verified against
+	ServerName string
+	// InsecureSkipVerify disables server certificate verification
+	InsecureSkipVerify bool
+}
+
+// Config builds a tls.Config
+func (c *TLSConfig) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read CA file")
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s contains no PEM certificates", c.CAFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("client certificates need both CertFile and KeyFile")
+		}
+
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// transport builds a transport for the target, or returns nil if the default will do
+func (tg *Target) transport() (*http.Transport, error) {
+	if tg.Socket == "" && tg.TLS == nil {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if tg.TLS != nil {
+		config, err := tg.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = config
+	}
+
+	if tg.Socket != "" {
+		socket := tg.Socket
+		dialer := &net.Dialer{}
+
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+
+		transport.DialTLSContext = nil
+	}
+
+	return transport, nil
+}
+
+// targetURL returns the base URL that requests are sent to. An explicit Target comes first,
+// then the environment's BaseUrl and Port variables, then the tester's Hostname and Port
+func (t *Tester) targetURL(scope *postman.Scope) (*url.URL, error) {
+	vars := scope.Map()
+
+	if t.Target != nil && (t.Target.BaseURL != "" || t.Target.Socket != "") {
+		raw := t.Target.BaseURL
+		if raw == "" {
+			raw = "http://localhost"
+		}
+
+		return parseBaseURL("Target.BaseURL", raw, vars)
+	}
+
+	host, port := t.Hostname, t.Port
+
+	if envHost, ok := vars["BaseUrl"]; ok && envHost != "" {
+		host = envHost
+	}
+
+	if envPort, ok := vars["Port"]; ok && envPort != "" {
+		port = envPort
+	}
+
+	if host == "" {
+		return nil, errors.New("no target configured: set Tester.Target, the BaseUrl environment variable or Tester.Hostname")
+	}
+
+	if strings.Contains(host, "://") {
+		base, err := parseBaseURL("BaseUrl", host, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		if base.Port() == "" && port != "" {
+			base.Host = net.JoinHostPort(base.Hostname(), port)
+		}
+
+		return base, nil
+	}
+
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	return parseBaseURL("BaseUrl", "http://"+host, vars)
+}
+
+// parseBaseURL substitutes variables into a base URL and checks that it is usable
+func parseBaseURL(name, raw string, vars map[string]string) (*url.URL, error) {
+	subst, err := postman.SubstVars(raw, vars)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to substitute variables in %s %s", name, raw)
+	}
+
+	base, err := url.Parse(subst)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s %s", name, subst)
+	}
+
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid %s %s: scheme must be http or https", name, subst)
+	}
+
+	if base.Host == "" {
+		return nil, fmt.Errorf("invalid %s %s: no host", name, subst)
+	}
+
+	return base, nil
+}
+
+// setTarget points req at the server under test
+func (t *Tester) setTarget(req *http.Request, scope *postman.Scope) error {
+	base, err := t.targetURL(scope)
+	if err != nil {
+		return err
+	}
+
+	pointAt(req, base)
+
+	return nil
+}
+
+// pointAt sends req to the scheme and host of base, under its path prefix
+func pointAt(req *http.Request, base *url.URL) {
+	req.URL.Scheme = base.Scheme
+	req.URL.Host = base.Host
+	req.Host = ""
+
+	if prefix := strings.TrimSuffix(base.Path, "/"); prefix != "" {
+		req.URL.Path = prefix + "/" + strings.TrimPrefix(req.URL.Path, "/")
+
+		if req.URL.RawPath != "" {
+			req.URL.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + "/" + strings.TrimPrefix(req.URL.RawPath, "/")
+		}
+	}
+}
+
+// client returns the client to send requests with, configured for the target and using the cookie jar
+func (t *Tester) client() (*http.Client, error) {
+	client, err := t.targetedClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Jar != nil {
+		return client, nil
+	}
+
+	withJar := *client
+	withJar.Jar = t.Jar()
+
+	return &withJar, nil
+}
+
+// targetedClient returns t.Client, with its transport replaced if the target needs one of its own
+func (t *Tester) targetedClient() (*http.Client, error) {
+	if t.Target == nil {
+		return t.Client, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.targetClient != nil && t.targetClientFor == t.Target {
+		return t.targetClient, nil
+	}
+
+	transport, err := t.Target.transport()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid target")
+	}
+
+	if transport == nil {
+		return t.Client, nil
+	}
+
+	client := *t.Client
+	client.Transport = transport
+
+	t.targetClient = &client
+	t.targetClientFor = t.Target
+
+	return t.targetClient, nil
+}
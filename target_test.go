@@ -0,0 +1,135 @@
+package gopherman
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		tester *Tester
+		env    map[string]string
+		want   string
+		err    string
+	}{
+		{
+			name:   "target base URL wins",
+			tester: &Tester{Target: &Target{BaseURL: "https://{{host}}:8443/v1"}, Hostname: "ignored"},
+			env:    map[string]string{"host": "api.local", "BaseUrl": "http://ignored"},
+			want:   "https://api.local:8443/v1",
+		},
+		{
+			name:   "socket defaults to localhost",
+			tester: &Tester{Target: &Target{Socket: "/tmp/api.sock"}},
+			want:   "http://localhost",
+		},
+		{
+			name:   "environment BaseUrl and Port",
+			tester: &Tester{Hostname: "ignored", Port: "1"},
+			env:    map[string]string{"BaseUrl": "api.local", "Port": "8080"},
+			want:   "http://api.local:8080",
+		},
+		{
+			name: "environment BaseUrl with a scheme and path",
+			env:  map[string]string{"BaseUrl": "https://api.local/v2", "Port": "8443"},
+			want: "https://api.local:8443/v2",
+		},
+		{
+			name:   "environment port overrides the tester's",
+			tester: &Tester{Hostname: "api.local", Port: "1"},
+			env:    map[string]string{"Port": "8080"},
+			want:   "http://api.local:8080",
+		},
+		{
+			name:   "tester hostname and port",
+			tester: &Tester{Hostname: "api.local", Port: "3002"},
+			want:   "http://api.local:3002",
+		},
+		{
+			name: "no target",
+			err:  "no target configured",
+		},
+		{
+			name:   "empty target",
+			tester: &Tester{Target: &Target{}},
+			err:    "no target configured",
+		},
+		{
+			name:   "bad scheme",
+			tester: &Tester{Target: &Target{BaseURL: "ftp://api.local"}},
+			err:    "scheme must be http or https",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scope := postman.NewScope()
+			for k, v := range test.env {
+				scope.SetEnvironment(k, v)
+			}
+
+			tester := test.tester
+			if tester == nil {
+				tester = &Tester{}
+			}
+
+			base, err := tester.targetURL(scope)
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.err)
+				}
+				return
+			}
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.want, base.String())
+			}
+		})
+	}
+}
+
+func TestTargetSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopherman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.URL.Path)
+	})}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	tester := &Tester{
+		Client: http.DefaultClient,
+		Target: &Target{BaseURL: "http://api.local/v1", Socket: socket},
+	}
+
+	req := &postman.Request{Method: http.MethodGet, URL: postman.URL{Raw: "http://localhost/things"}}
+
+	httpReq, client, err := tester.buildRequest(req, postman.NewScope())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, _, err := makeRequest(client, httpReq)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "api.local /v1/things", actual.Raw)
+	}
+}
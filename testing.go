@@ -1,141 +1,543 @@
 package gopherman
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/cohix/gopherman/jsonschema"
 	"github.com/cohix/gopherman/postman"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// EnvVarPrefix is the prefix of OS environment variables that override environment values,
+// so that CI can inject secrets without editing committed environment files
+const EnvVarPrefix = "GOPHERMAN_ENV_"
+
 // Tester represents a collection test tool
 type Tester struct {
 	Environment *postman.Environment
+	Globals     *postman.Environment
+	Vars        *postman.Scope
+	Secrets     *postman.Secrets
 	Client      *http.Client
 	Collections []postman.Collection
-	Hostname    string
-	Port        string
+
+	// Hostname and Port are the target if neither Target nor the environment's BaseUrl is set.
+	// There's no default, so a tester with no target configured fails to send requests
+	Hostname string
+	Port     string
+
+	// Handler compares responses for items run by Run, DefaultHandler if nil
+	Handler Handler
+
+	// Comparator compares response bodies in DefaultHandler and TestHelper.CompareBody
+	Comparator *Comparator
+
+	extractions map[string][]postman.Extraction
+
+	// Target is where requests are sent. If it's nil, the environment's BaseUrl and Port
+	// variables are used, falling back to Hostname and Port
+	Target *Target
+
+	// Timeout limits how long each request may take, RunTimeout how long a whole run may take
+	Timeout    time.Duration
+	RunTimeout time.Duration
+
+	// Parallel is how many independent items may run at once. See RunContext
+	Parallel int
+
+	// Readiness, if set, must pass before the first request is sent. See WaitReady
+	Readiness *Readiness
+
+	// DefaultRetry is the retry policy for items without one of their own
+	DefaultRetry *postman.RetryPolicy
+
+	// Reporters are given the results of every item run, by WriteReports and at the end of RunContext and RunIterations
+	Reporters []Reporter
+
+	// SkipMissingExamples skips items with no examples, instead of failing them
+	SkipMissingExamples bool
+
+	// IgnoreHeaders are left out when comparing response headers with examples, along with VolatileHeaders
+	IgnoreHeaders []string
+
+	// Cookies seed the cookie jar at the start of each run. See ResetCookies
+	Cookies []postman.Cookie
+
+	// Update records actual responses as the items' examples instead of comparing against them,
+	// then rewrites the collection files. It's also enabled by $GOPHERMAN_UPDATE, or -gopherman.update after RegisterUpdateFlag
+	Update bool
+
+	targetClient    *http.Client
+	targetClientFor *Target
+	independent     map[string]bool
+	retries         map[string]*postman.RetryPolicy
+	schemas         map[string]*jsonschema.Schema
+	schemaFiles     map[string]*jsonschema.Schema
+	headers         map[string][]postman.HeaderAssertion
+	jar             http.CookieJar
+	ready           bool
+	report          *Report
+	files           []string
+	overlay         map[string]string
+	overlaid        map[string]string
+	updates         []exampleUpdate
+	mu              sync.Mutex
 }
 
-// NewTesterWithCollection loads a collection from a file
+// NewTesterWithCollection loads a collection from a file. An empty envFile means an empty environment
 func NewTesterWithCollection(path string, envFile string, files ...string) (*Tester, error) {
-	env, err := postman.EnvironmentFromFile(filepath.Join(path, envFile))
-	if err != nil {
-		return nil, err
+	env := &postman.Environment{}
+
+	if envFile != "" {
+		var err error
+		if env, err = postman.EnvironmentFromFile(filepath.Join(path, envFile)); err != nil {
+			return nil, err
+		}
 	}
 
 	collections := make([]postman.Collection, len(files))
+	paths := make([]string, len(files))
 
 	for i, name := range files {
-		file, err := ioutil.ReadFile(filepath.Join(path, name))
-		if err != nil {
-			return nil, err
-		}
+		paths[i] = filepath.Join(path, name)
 
-		collection := postman.Collection{}
-		if err := json.Unmarshal(file, &collection); err != nil {
+		collection, err := postman.CollectionFromFile(paths[i])
+		if err != nil {
 			return nil, err
 		}
 
-		collections[i] = collection
+		collections[i] = *collection
 	}
 
+	secrets := postman.NewSecrets()
+	secrets.Register("file", postman.FileSecretProvider{Dir: path})
+
 	tester := Tester{
-		Environment: env,
+		Vars:        postman.NewScope(),
+		Secrets:     secrets,
 		Client:      http.DefaultClient,
 		Collections: collections,
-		Hostname:    "localhost",
-		Port:        "3002",
+		Comparator:  NewComparator(),
+		files:       paths,
+		overlay:     postman.OSVariables(EnvVarPrefix),
+	}
+
+	if err := tester.UseEnvironment(env); err != nil {
+		return nil, err
 	}
 
 	return &tester, nil
 }
 
-// TestRequestWithName finds the named request in the collection, makes the same request, and then returns the request, expected response, and actual response
-func (t *Tester) TestRequestWithName(name string, tst *testing.T, handler func(*TestHelper, *postman.Request, *postman.Response, *postman.Response)) []error {
-	vars := t.Environment.VariableMap()
+// RegisterSecretProvider adds a provider for secret references starting with scheme:,
+// then resolves the environment and globals again so they can use it
+func (t *Tester) RegisterSecretProvider(scheme string, provider postman.SecretProvider) error {
+	t.Secrets.Register(scheme, provider)
+
+	if err := t.UseEnvironment(t.Environment); err != nil {
+		return err
+	}
+
+	if t.Globals != nil {
+		return t.SetGlobals(t.Globals)
+	}
+
+	return nil
+}
 
-	tmplHost, err := postman.SubstVars("{{ .BaseUrl }}:{{ .Port }}", vars)
+// SetGlobals sets the globals used as the lowest precedence variable level
+func (t *Tester) SetGlobals(globals *postman.Environment) error {
+	vars, err := globals.ResolvedVariableMap(t.Secrets)
 	if err != nil {
-		fmt.Println(err)
-		tmplHost = "localhost:8080"
+		return errors.Wrap(err, "failed to resolve globals")
 	}
 
+	t.Globals = globals
+	t.Vars.Global = vars
+
+	return nil
+}
+
+// LoadGlobals loads a Postman globals export and uses it as the tester's globals
+func (t *Tester) LoadGlobals(path string) error {
+	globals, err := postman.GlobalsFromFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to GlobalsFromFile")
+	}
+
+	return t.SetGlobals(globals)
+}
+
+// UseEnvironment replaces the tester's environment, discarding any environment values set at runtime.
+// Values from $GOPHERMAN_ENV_* variables and LoadDotEnv still override it
+func (t *Tester) UseEnvironment(env *postman.Environment) error {
+	vars, err := env.ResolvedVariableMap(t.Secrets)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve environment")
+	}
+
+	t.Environment = env
+	t.Vars.Environment = vars
+	t.overlaid = map[string]string{}
+
+	return t.applyOverlay(t.overlay)
+}
+
+// LoadDotEnv overlays the values from a .env file onto the tester's environment values.
+// They're never written to the environment file by SaveEnvironment
+func (t *Tester) LoadDotEnv(path string) error {
+	vars, err := postman.DotEnvFromFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to DotEnvFromFile")
+	}
+
+	if t.overlay == nil {
+		t.overlay = map[string]string{}
+	}
+
+	for k, v := range vars {
+		t.overlay[k] = v
+	}
+
+	return t.applyOverlay(vars)
+}
+
+// applyOverlay resolves vars and sets them as environment values, remembering them so they aren't saved
+func (t *Tester) applyOverlay(vars map[string]string) error {
+	if t.overlaid == nil {
+		t.overlaid = map[string]string{}
+	}
+
+	for k, v := range vars {
+		val, err := t.Secrets.Resolve(postman.Variable{Key: k, Value: v})
+		if err != nil {
+			return err
+		}
+
+		t.Vars.Environment[k] = val
+		t.overlaid[k] = val
+	}
+
+	return nil
+}
+
+// SaveEnvironment writes the environment to path, including any current values set during runs.
+// Values overlaid from OS variables or .env files are left out
+func (t *Tester) SaveEnvironment(path string) error {
+	syncVariables(t.Environment, t.Vars.Environment, t.Secrets, t.overlaid)
+
+	return t.Environment.WriteFile(path)
+}
+
+// SaveEncryptedEnvironment is like SaveEnvironment, but encrypts the file with passphrase
+func (t *Tester) SaveEncryptedEnvironment(path string, passphrase []byte) error {
+	syncVariables(t.Environment, t.Vars.Environment, t.Secrets, t.overlaid)
+
+	return t.Environment.WriteEncryptedFile(path, passphrase)
+}
+
+// SaveGlobals writes the globals to path, including any current values set during runs
+func (t *Tester) SaveGlobals(path string) error {
+	if t.Globals == nil {
+		t.Globals = &postman.Environment{
+			EnvMeta: postman.EnvMeta{VariableScope: "globals"},
+			Name:    "Globals",
+		}
+	}
+
+	syncVariables(t.Globals, t.Vars.Global, t.Secrets, nil)
+
+	return t.Globals.WriteFile(path)
+}
+
+// syncVariables copies current values into env wherever they differ.
+// Resolved secrets are never copied, so references aren't replaced by plaintext,
+// and nor are values still as overlaid, which may be credentials injected by CI
+func syncVariables(env *postman.Environment, current map[string]string, secrets *postman.Secrets, overlaid map[string]string) {
+	changed := map[string]string{}
+
+	for k, v := range current {
+		if secrets.IsSecret(v) {
+			continue
+		}
+
+		if val, ok := overlaid[k]; ok && val == v {
+			continue
+		}
+
+		if old, ok := env.Get(k); !ok || old != v {
+			changed[k] = v
+		}
+	}
+
+	env.Overlay(changed)
+}
+
+// scopeFor returns the variable scope to use for requests from collection
+func (t *Tester) scopeFor(collection *postman.Collection) (*postman.Scope, error) {
+	vars, err := collection.ResolvedVariableMap(t.Secrets)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve collection variables")
+	}
+
+	return t.Vars.WithCollection(vars), nil
+}
+
+// Validate checks every collection with postman.Validate, resolving variables through the tester's scope
+func (t *Tester) Validate() []error {
 	errs := []error{}
 
-	for _, collection := range t.Collections {
-		helper := NewTestHelper(tst)
+	for i := range t.Collections {
+		collection := &t.Collections[i]
+
+		scope, err := t.scopeFor(collection)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "(collection %s)", collection.Info.Name))
+			continue
+		}
+
+		for _, e := range postman.Validate(collection, scope.Map()) {
+			errs = append(errs, errors.Wrapf(e, "(collection %s)", collection.Info.Name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// TestRequestWithName finds the named request in the collection, makes the same request, and then returns the request, expected response, and actual response
+func (t *Tester) TestRequestWithName(name string, tst *testing.T, handler func(*TestHelper, *postman.Request, *postman.Response, *postman.Response)) []error {
+	return t.TestRequestWithNameContext(context.Background(), name, tst, handler)
+}
 
-		// put this in a func so that critical errors can be collected and then bail out
-		func() {
-			itm := collection.ItemWithName(name)
-			if itm == nil {
-				helper.Error(fmt.Errorf("item with name %s doesn't exist", name))
-				return
-			}
+// TestRequestWithNameContext is like TestRequestWithName, but the request is cancelled if ctx is done
+func (t *Tester) TestRequestWithNameContext(ctx context.Context, name string, tst *testing.T, handler func(*TestHelper, *postman.Request, *postman.Response, *postman.Response)) []error {
+	return t.testRequest(ctx, name, firstExample, tst, handler)
+}
 
-			httpReq := itm.Request.ToHTTPRequest(vars)
-			if httpReq == nil {
-				helper.Error(errors.New("failed to build HTTP request"))
-				return
-			}
+// TestRequestWithExample is like TestRequestWithName, but compares against the item's example with
+// the given name. If the example has an originalRequest, that request is sent instead of the item's
+func (t *Tester) TestRequestWithExample(name, example string, tst *testing.T, handler func(*TestHelper, *postman.Request, *postman.Response, *postman.Response)) []error {
+	return t.testRequest(context.Background(), name, exampleNamed(example), tst, handler)
+}
 
-			httpReq.URL.Host = tmplHost
-			httpReq.URL.Scheme = "http"
+// TestRequestWithStatus is like TestRequestWithExample, but chooses the item's first example with status
+func (t *Tester) TestRequestWithStatus(name string, status int, tst *testing.T, handler func(*TestHelper, *postman.Request, *postman.Response, *postman.Response)) []error {
+	return t.testRequest(context.Background(), name, exampleWithStatus(status), tst, handler)
+}
 
-			actual, err := makeRequest(t.Client, httpReq)
-			if err != nil {
-				helper.Error(err)
-				return
-			}
+func (t *Tester) testRequest(ctx context.Context, name string, pick exampleSelector, tst *testing.T, handler Handler) []error {
+	if err := t.WaitReady(ctx); err != nil {
+		return []error{err}
+	}
 
-			handler(helper, &itm.Request, &itm.Response[0], actual)
-		}()
+	errs := []error{}
+	skipped := 0
+
+	for i := range t.Collections {
+		collection := &t.Collections[i]
+
+		helper := t.newHelper(tst)
+
+		itm := collection.ItemWithName(name)
+		if itm == nil {
+			helper.Error(fmt.Errorf("item with name %s doesn't exist", name))
+		} else if t.runItem(ctx, helper, collection, name, itm, pick, handler) {
+			skipped++
+		}
 
 		if helper.HasErrors() {
 			errs = append(errs, helper.AnnotateErrors(collection.Info.Name, name)...)
 		}
 	}
 
+	if err := t.WriteUpdates(); err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
 
+	if skipped > 0 && skipped == len(t.Collections) {
+		tst.Skipf("item with name %s has no example responses", name)
+	}
+
 	return nil
 }
 
-func makeRequest(client *http.Client, req *http.Request) (*postman.Response, error) {
+func (t *Tester) newHelper(tst *testing.T) *TestHelper {
+	helper := NewTestHelper(tst)
+	helper.secrets = t.Secrets
+
+	if t.Comparator != nil {
+		helper.Comparator = t.Comparator
+	}
+
+	helper.IgnoreHeaders = t.IgnoreHeaders
+
+	return helper
+}
+
+// runItem makes the request for the example chosen by pick and passes the result to handler,
+// collecting any errors in helper. It returns true if the item was skipped for having no examples
+func (t *Tester) runItem(ctx context.Context, helper *TestHelper, collection *postman.Collection, path string, itm *postman.CollectionItem, pick exampleSelector, handler Handler) bool {
+	result := &Result{
+		Collection: collection.Info.Name,
+		Path:       path,
+		Start:      time.Now(),
+	}
+
+	defer t.record(result, helper)
+
+	update := t.updating()
+
+	index, err := pick(itm.Response)
+	switch {
+	case err == errNoExamples && update:
+		// the actual response becomes the first example
+	case err == errNoExamples && t.hasSchema(itm):
+		// the response is only validated against the schema
+	case err == errNoExamples && t.SkipMissingExamples:
+		result.Skipped = true
+		return true
+	case err != nil:
+		helper.Error(errors.Wrapf(err, "item with name %s", itm.Name))
+		return false
+	}
+
+	req := &itm.Request
+
+	var expected *postman.Response
+	if index < len(itm.Response) {
+		expected = &itm.Response[index]
+
+		if expected.OriginalRequest != nil && expected.OriginalRequest.Method != "" {
+			req = expected.OriginalRequest
+		}
+	}
+
+	scope, err := t.scopeFor(collection)
+	if err != nil {
+		helper.Error(err)
+		return false
+	}
+
+	helper.Vars = scope
+
+	httpReq, client, err := t.buildRequest(req, scope)
+	if err != nil {
+		helper.Error(err)
+		return false
+	}
+
+	result.Request = dumpRequest(httpReq)
+	result.Expected = expected
+
+	helper.jar = client.Jar
+	helper.url = httpReq.URL
+
+	actual, header, err := t.send(ctx, client, httpReq, t.retryPolicyFor(itm), helper)
+	if err != nil {
+		helper.Error(err)
+		return false
+	}
+
+	result.Response = actual
+	result.Duration = time.Since(result.Start)
+
+	if err := extract(scope, t.extractionsFor(itm), actual, header); err != nil {
+		helper.Error(err)
+	}
+
+	if update {
+		t.queueUpdate(collection, path, itm, index, actual)
+		return false
+	}
+
+	t.validateSchema(helper, collection, itm, actual)
+	t.checkHeaders(helper, itm, actual)
+
+	if expected != nil {
+		handler(helper, req, expected, actual)
+	}
+
+	return false
+}
+
+// buildRequest converts req to an http request aimed at the target, and returns the client to send it with
+func (t *Tester) buildRequest(req *postman.Request, scope *postman.Scope) (*http.Request, *http.Client, error) {
+	httpReq, err := req.HTTPRequestWithScope(scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := t.setTarget(httpReq, scope); err != nil {
+		return nil, nil, err
+	}
+
+	client, err := t.client()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return httpReq, client, nil
+}
+
+func makeRequest(client *http.Client, req *http.Request) (*postman.Response, http.Header, error) {
+	start := time.Now()
+
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	actual := &postman.Response{
-		Mode:   "raw",
-		Raw:    string(body),
-		Status: resp.StatusCode,
+		Mode:         "raw",
+		Raw:          string(body),
+		Status:       resp.StatusCode,
+		StatusText:   strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+		Header:       postman.HeadersFromHTTP(resp.Header),
+		Cookie:       []postman.Cookie{},
+		ResponseTime: time.Since(start).Milliseconds(),
+	}
+
+	for _, c := range resp.Cookies() {
+		actual.Cookie = append(actual.Cookie, postman.CookieFromHTTP(c, req.URL.Hostname()))
 	}
 
-	return actual, nil
+	return actual, resp.Header, nil
 }
 
 ////////// helper functions //////////
 
-// AssertErrors loops through the collected errors and t.Error's each of them
+// AssertErrors loops through the collected errors and t.Error's each of them.
+// Errors returned by Tester already have secret values masked
 func AssertErrors(t *testing.T, errs []error) {
+	t.Helper()
+
 	for _, e := range errs {
 		t.Error(e)
 	}
@@ -145,19 +547,38 @@ func AssertErrors(t *testing.T, errs []error) {
 
 // TestHelper helps with running tests
 type TestHelper struct {
-	Assert *assert.Assertions
-	t      *testing.T
-	errors []error
+	Assert     *assert.Assertions
+	Vars       *postman.Scope
+	Comparator *Comparator
+
+	// Attempts is how many times the request was sent, more than 1 if it was retried
+	Attempts int
+
+	// Schema is the JSON Schema the response was validated against, if the item has one
+	Schema *jsonschema.Schema
+
+	// IgnoreHeaders are left out of CompareHeaders, along with VolatileHeaders
+	IgnoreHeaders []string
+
+	t        *testing.T
+	errors   []error
+	failures []string
+	secrets  *postman.Secrets
+	jar      http.CookieJar
+	url      *url.URL
 }
 
 // NewTestHelper creates a new test helper
 func NewTestHelper(t *testing.T) *TestHelper {
 	helper := &TestHelper{
-		Assert: assert.New(t),
-		t:      t,
-		errors: []error{},
+		Vars:       postman.NewScope(),
+		Comparator: NewComparator(),
+		t:          t,
+		errors:     []error{},
 	}
 
+	helper.Assert = assert.New(&maskingT{helper: helper})
+
 	return helper
 }
 
@@ -170,9 +591,38 @@ func (t *TestHelper) Error(err error) {
 	t.errors = append(t.errors, err)
 }
 
-// Log logs something
+// CompareBody compares the expected and actual bodies using the helper's Comparator,
+// collecting a path-by-path diff as an error if they differ
+func (t *TestHelper) CompareBody(expected, actual *postman.Response) bool {
+	diffs := t.Comparator.Compare([]byte(expected.Raw), []byte(actual.Raw))
+	if len(diffs) == 0 {
+		return true
+	}
+
+	t.Error(fmt.Errorf("body differs from example:\n%s", FormatDifferences(diffs)))
+
+	return false
+}
+
+// Set sets a runtime variable, visible to every later request in the run
+func (t *TestHelper) Set(key, value string) {
+	t.Vars.Set(key, value)
+}
+
+// SetEnvironment sets an environment value, which is persisted by Tester.SaveEnvironment
+func (t *TestHelper) SetEnvironment(key, value string) {
+	t.Vars.SetEnvironment(key, value)
+}
+
+// SetGlobal sets a global value, which is persisted by Tester.SaveGlobals
+func (t *TestHelper) SetGlobal(key, value string) {
+	t.Vars.SetGlobal(key, value)
+}
+
+// Log logs something, with secret values masked
 func (t *TestHelper) Log(msg string) {
-	t.t.Log(msg)
+	t.t.Helper()
+	t.t.Log(t.secrets.Mask(msg))
 }
 
 // AnnotateErrors adds collection and test names to errors held by t
@@ -181,8 +631,45 @@ func (t *TestHelper) AnnotateErrors(collectionName, testName string) []error {
 
 	for _, e := range t.errors {
 		wrapped := errors.Wrapf(e, "(collection %s, request %s)", collectionName, testName)
-		errs = append(errs, wrapped)
+		errs = append(errs, t.mask(wrapped))
 	}
 
 	return errs
 }
+
+func (t *TestHelper) mask(err error) error {
+	if t.secrets == nil {
+		return err
+	}
+
+	return &maskedError{err: err, msg: t.secrets.Mask(err.Error())}
+}
+
+// maskedError wraps an error whose message contained secret values
+type maskedError struct {
+	err error
+	msg string
+}
+
+func (m *maskedError) Error() string {
+	return m.msg
+}
+
+// Cause returns the original error, for errors.Cause
+func (m *maskedError) Cause() error {
+	return m.err
+}
+
+// maskingT masks secret values in assertion failures before they reach the test log
+type maskingT struct {
+	helper *TestHelper
+}
+
+// Errorf reports an assertion failure
+func (m *maskingT) Errorf(format string, args ...interface{}) {
+	m.helper.t.Helper()
+
+	msg := m.helper.secrets.Mask(fmt.Sprintf(format, args...))
+	m.helper.failures = append(m.helper.failures, msg)
+	m.helper.t.Error(msg)
+}
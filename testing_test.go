@@ -0,0 +1,61 @@
+package gopherman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cohix/gopherman/postman"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlaidValuesAreNotSaved(t *testing.T) {
+	dir := writeTempFile(t, "env.json", []byte(`{"name":"dev","values":[{"key":"host","value":"dev.local","enabled":true},{"key":"token","value":"committed","enabled":true}]}`))
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, ".env"), []byte("token=ci-dotenv-token\nextra=ci-extra\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv(EnvVarPrefix+"osToken", "ci-os-token")
+	defer os.Unsetenv(EnvVarPrefix + "osToken")
+
+	tester, err := NewTesterWithCollection(dir, "env.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, tester.LoadDotEnv(filepath.Join(dir, ".env")))
+
+	for k, v := range map[string]string{"host": "dev.local", "token": "ci-dotenv-token", "extra": "ci-extra", "osToken": "ci-os-token"} {
+		val, _ := tester.Vars.Get(k)
+		assert.Equal(t, v, val, k)
+	}
+
+	tester.Vars.SetEnvironment("session", "abc")
+	tester.Vars.SetEnvironment("extra", "changed at runtime")
+
+	path := filepath.Join(dir, "saved.json")
+	assert.NoError(t, tester.SaveEnvironment(path))
+
+	saved, err := postman.EnvironmentFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := saved.ResolvedVariableMap(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "dev.local", "token": "committed", "session": "abc", "extra": "changed at runtime"}, vars)
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "ci-dotenv-token")
+	assert.NotContains(t, string(data), "ci-os-token")
+
+	// overlays outlive a change of environment
+	assert.NoError(t, tester.UseEnvironment(&postman.Environment{}))
+
+	val, _ := tester.Vars.Get("token")
+	assert.Equal(t, "ci-dotenv-token", val)
+}